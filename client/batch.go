@@ -0,0 +1,145 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultMaxMessageSize 是单个 WriteRequest 的默认最大字节数，与 gRPC 默认的 4MB 接收上限保持一致。
+const DefaultMaxMessageSize = 4 * 1024 * 1024
+
+// writeRequestOverhead 为 WriteRequest 中 device_id、election_id 等非 update 字段预留的字节数
+const writeRequestOverhead = 64
+
+// Batch 累积多个 v1.Update，并通过尽可能少的 WriteRequest 一次性发送。
+//   - Atomicity：发送时使用的原子性模式（CONTINUE_ON_ERROR、ROLLBACK_ON_ERROR、DATAPLANE_ATOMIC）。
+//   - MaxMessageSize：单个 WriteRequest 的最大字节数，超过时自动拆分为多个请求。
+//
+// Batch 实现了 WriteUpdate，因此可以替代客户端传给 TableControl、DigestControl 等，
+// 使它们的写操作累积到批次中，而不是立即发送。
+type Batch struct {
+	client         EntityClient
+	updates        []*v1.Update
+	Atomicity      v1.WriteRequest_Atomicity
+	MaxMessageSize int
+}
+
+// NewBatch 创建一个新的批次，写操作最终通过 c 发送
+func NewBatch(c EntityClient, atomicity v1.WriteRequest_Atomicity) *Batch {
+	return &Batch{
+		client:         c,
+		Atomicity:      atomicity,
+		MaxMessageSize: DefaultMaxMessageSize,
+	}
+}
+
+// WriteUpdate 将 update 加入批次，不会立即发送
+func (b *Batch) WriteUpdate(update *v1.Update) error {
+	b.updates = append(b.updates, update)
+	return nil
+}
+
+// Updates 返回批次中已累积的所有 update
+func (b *Batch) Updates() []*v1.Update {
+	return b.updates
+}
+
+// Len 返回批次中已累积的 update 数量
+func (b *Batch) Len() int {
+	return len(b.updates)
+}
+
+// Reset 清空批次
+func (b *Batch) Reset() {
+	b.updates = nil
+}
+
+// Send 发送批次中的所有 update，全部成功后清空批次。
+// 当批次大小超过 MaxMessageSize 时按大小拆分为多个 WriteRequest；
+// 由于原子性只在单个 WriteRequest 内有效，非 CONTINUE_ON_ERROR 模式下需要拆分时会返回错误。
+// 某个 WriteRequest 失败时仍会继续发送之后的请求，返回所有请求的错误，批次中只保留没有写入交换机的 update：
+// CONTINUE_ON_ERROR 模式下服务端返回了逐条结果（见 WriteError）时只保留失败的 update，否则保留整个请求的 update。
+func (b *Batch) Send() error {
+	return b.SendContext(context.Background())
+}
+
+// SendContext 与 Send 相同，但使用 ctx 控制每个 WriteRequest 的截止时间和取消。
+// ctx 被取消后不再发送剩余的请求，这些 update 保留在批次中。
+func (b *Batch) SendContext(ctx context.Context) error {
+	if len(b.updates) == 0 {
+		return nil
+	}
+
+	chunks, err := b.chunks()
+	if err != nil {
+		return err
+	}
+	if len(chunks) > 1 && b.Atomicity != v1.WriteRequest_CONTINUE_ON_ERROR {
+		return fmt.Errorf("batch of %d updates exceeds the maximum message size of %d bytes and cannot be sent with %s atomicity",
+			len(b.updates), b.MaxMessageSize, b.Atomicity)
+	}
+
+	var unsent []*v1.Update
+	var errs []error
+	for idx, chunk := range chunks {
+		if err := ctx.Err(); err != nil {
+			for _, rest := range chunks[idx:] {
+				unsent = append(unsent, rest...)
+			}
+			errs = append(errs, err)
+			break
+		}
+		if err := b.client.WriteUpdatesContext(ctx, chunk, b.Atomicity); err != nil {
+			unsent = append(unsent, b.failedUpdates(chunk, err)...)
+			errs = append(errs, err)
+		}
+	}
+
+	b.updates = unsent
+	return errors.Join(errs...)
+}
+
+// failedUpdates 返回 chunk 中没有写入交换机的 update。只有 CONTINUE_ON_ERROR 模式下
+// 逐条结果为 OK 的 update 确实已经写入；其他模式下失败的请求整体回滚或结果未知，保留整个 chunk。
+func (b *Batch) failedUpdates(chunk []*v1.Update, err error) []*v1.Update {
+	var writeErr *WriteError
+	if b.Atomicity == v1.WriteRequest_CONTINUE_ON_ERROR && errors.As(err, &writeErr) && len(writeErr.Updates) == len(chunk) {
+		return writeErr.FailedUpdates()
+	}
+	return chunk
+}
+
+// chunks 按 MaxMessageSize 将 update 拆分为多组
+func (b *Batch) chunks() ([][]*v1.Update, error) {
+	maxSize := b.MaxMessageSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxMessageSize
+	}
+
+	var chunks [][]*v1.Update
+	var current []*v1.Update
+	currentSize := writeRequestOverhead
+	for _, update := range b.updates {
+		// 每个 update 额外需要字段标签和长度前缀
+		size := proto.Size(update) + 1 + protowire.SizeVarint(uint64(proto.Size(update)))
+		if size+writeRequestOverhead > maxSize {
+			return nil, errors.New("a single update exceeds the maximum message size")
+		}
+		if currentSize+size > maxSize {
+			chunks = append(chunks, current)
+			current = nil
+			currentSize = writeRequestOverhead
+		}
+		current = append(current, update)
+		currentSize += size
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks, nil
+}
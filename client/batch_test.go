@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakeWriter 记录每个 WriteRequest 中的 update，第 n 个请求（从 1 开始）返回 errs[n]
+type fakeWriter struct {
+	EntityClient
+	requests [][]*v1.Update
+	errs     map[int]func([]*v1.Update) error
+}
+
+func (f *fakeWriter) WriteUpdatesContext(_ context.Context, updates []*v1.Update, _ v1.WriteRequest_Atomicity) error {
+	f.requests = append(f.requests, updates)
+	if errFn, ok := f.errs[len(f.requests)]; ok {
+		return errFn(updates)
+	}
+	return nil
+}
+
+// plainError 模拟没有逐条结果的失败
+func plainError([]*v1.Update) error {
+	return errors.New("write failed")
+}
+
+// failLast 模拟逐条结果中只有最后一个 update 失败的 WriteError
+func failLast(updates []*v1.Update) error {
+	writeErr := &WriteError{Status: status.New(codes.Unknown, "write failed")}
+	for idx, u := range updates {
+		code := codes.OK
+		if idx == len(updates)-1 {
+			code = codes.AlreadyExists
+		}
+		writeErr.Updates = append(writeErr.Updates, &UpdateError{Update: u, CanonicalCode: code})
+	}
+	return writeErr
+}
+
+// testUpdate 返回一个编码后约为 size 字节的 update
+func testUpdate(id uint32, size int) *v1.Update {
+	return &v1.Update{
+		Type: v1.Update_INSERT,
+		Entity: &v1.Entity{Entity: &v1.Entity_TableEntry{TableEntry: &v1.TableEntry{
+			TableId:  id,
+			Metadata: make([]byte, size),
+		}}},
+	}
+}
+
+func TestBatchChunks(t *testing.T) {
+	small := testUpdate(1, 100)
+	size := proto.Size(small) + 1 + protowire.SizeVarint(uint64(proto.Size(small)))
+
+	tests := []struct {
+		name       string
+		count      int
+		maxSize    int
+		wantChunks []int
+		wantErr    bool
+	}{
+		{"empty batch", 0, 1024, nil, false},
+		{"fits in one request", 3, writeRequestOverhead + 3*size, []int{3}, false},
+		{"split at the size limit", 3, writeRequestOverhead + 2*size, []int{2, 1}, false},
+		{"one update per request", 3, writeRequestOverhead + size, []int{1, 1, 1}, false},
+		{"default size when unset", 3, 0, []int{3}, false},
+		{"single update too large", 1, writeRequestOverhead + size - 1, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBatch(nil, v1.WriteRequest_CONTINUE_ON_ERROR)
+			b.MaxMessageSize = tt.maxSize
+			for i := 0; i < tt.count; i++ {
+				_ = b.WriteUpdate(small)
+			}
+			chunks, err := b.chunks()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("chunks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(chunks) != len(tt.wantChunks) {
+				t.Fatalf("chunks() returned %d chunks, want %d", len(chunks), len(tt.wantChunks))
+			}
+			for i, chunk := range chunks {
+				if len(chunk) != tt.wantChunks[i] {
+					t.Errorf("chunk %d has %d updates, want %d", i, len(chunk), tt.wantChunks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBatchSendKeepsUnsentUpdates(t *testing.T) {
+	updates := []*v1.Update{testUpdate(1, 100), testUpdate(2, 100), testUpdate(3, 100), testUpdate(4, 100)}
+	size := proto.Size(updates[0]) + 1 + protowire.SizeVarint(uint64(proto.Size(updates[0])))
+	continueOnError := v1.WriteRequest_CONTINUE_ON_ERROR
+
+	tests := []struct {
+		name      string
+		atomicity v1.WriteRequest_Atomicity
+		perChunk  int
+		errs      map[int]func([]*v1.Update) error
+		wantSent  int
+		wantLeft  []uint32
+		wantErr   bool
+	}{
+		{"all chunks succeed", continueOnError, 1, nil, 4, nil, false},
+		{"first chunk fails", continueOnError, 1, map[int]func([]*v1.Update) error{1: plainError}, 4, []uint32{1}, true},
+		{"first and third chunks fail", continueOnError, 1, map[int]func([]*v1.Update) error{1: plainError, 3: plainError}, 4, []uint32{1, 3}, true},
+		{"whole chunk kept without per-update results", continueOnError, 2, map[int]func([]*v1.Update) error{2: plainError}, 2, []uint32{3, 4}, true},
+		{"only failed updates kept", continueOnError, 2, map[int]func([]*v1.Update) error{1: failLast, 2: failLast}, 2, []uint32{2, 4}, true},
+		{"rolled back chunk kept whole", v1.WriteRequest_ROLLBACK_ON_ERROR, 4, map[int]func([]*v1.Update) error{1: failLast}, 1, []uint32{1, 2, 3, 4}, true},
+		{"atomic batch cannot be split", v1.WriteRequest_ROLLBACK_ON_ERROR, 1, nil, 0, []uint32{1, 2, 3, 4}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &fakeWriter{errs: tt.errs}
+			b := NewBatch(w, tt.atomicity)
+			b.MaxMessageSize = writeRequestOverhead + tt.perChunk*size
+			for _, u := range updates {
+				_ = b.WriteUpdate(u)
+			}
+
+			err := b.Send()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(w.requests) != tt.wantSent {
+				t.Errorf("Send() sent %d requests, want %d", len(w.requests), tt.wantSent)
+			}
+			var left []uint32
+			for _, u := range b.Updates() {
+				left = append(left, u.Entity.GetTableEntry().TableId)
+			}
+			if len(left) != len(tt.wantLeft) {
+				t.Fatalf("batch kept tables %v, want %v", left, tt.wantLeft)
+			}
+			for i := range left {
+				if left[i] != tt.wantLeft[i] {
+					t.Errorf("batch kept tables %v, want %v", left, tt.wantLeft)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestBatchSendReturnsWriteErrors(t *testing.T) {
+	w := &fakeWriter{errs: map[int]func([]*v1.Update) error{1: failLast}}
+	b := NewBatch(w, v1.WriteRequest_CONTINUE_ON_ERROR)
+	_ = b.WriteUpdate(testUpdate(1, 10))
+
+	var writeErr *WriteError
+	if err := b.Send(); !errors.As(err, &writeErr) {
+		t.Fatalf("Send() error = %v, want a *WriteError", err)
+	}
+}
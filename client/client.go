@@ -3,11 +3,12 @@ package client
 import (
 	"context"
 	"fmt"
-	"github.com/golang/protobuf/proto"
 	"google.golang.org/grpc"
 	"io"
 	"io/ioutil"
 	"log"
+	"sync"
+	"time"
 
 	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
 	"github.com/p4lang/p4runtime/go/p4/v1"
@@ -20,51 +21,112 @@ import (
 // - isMaster: 客户端是否为主节点。
 // - electionID: 选举 ID。
 // - p4Info: P4 信息。
+// - savedP4Info: 通过 VERIFY_AND_SAVE 保存、尚未生效的 P4 信息。
 // - IncomingMessageChannel: 接收消息的通道。
-// - OutgoingMessageChannel: 发送消息的通道。
-// - streamChannel: gRPC 流通道。
-// - Entities: 存储实体的映射。
+// - OutgoingMessageChannel: 发送消息的通道，容量为 OutgoingBufferSize，应通过 SendMessage 以非阻塞方式发送。
+// - unsent: 上一个流上发送失败的消息，重连后最先发送。
+// - streamChannel: 当前的 gRPC 流通道，重连后会被替换。
+// - Entities: 存储实体的映射，加载新的 P4Info 时整体替换；与加载并发读取时应使用 GetEntities。
+// - index: 按名称、别名和 ID 索引 Entities 中的实体。
+// - p4Info、Entities、index 和角色状态由 stateMu 保护。
+// - state: 流的连接状态，状态变化会发送到 stateChannel。
+// - reconnectPolicy: 流断开后的重连策略。
+// - timeout: 单次 RPC 的默认超时时间，0 表示不设置超时，不作用于 Read 流。
+// - timeoutSet: 是否已经通过 SetDefaultTimeout 设置了超时时间，Init 不会覆盖已经设置的值。
+// - role: 客户端的角色，为 nil 时使用默认角色。
+// - roleEntityIDs/roleErr: 由 role.Entities 解析出的实体 ID 及解析错误，在设置角色或加载 P4Info 时更新。
+// - conn/ctx/cancel: gRPC 连接和客户端的生命周期，Close 时取消 ctx 并关闭 conn。
 type Client struct {
 	v1.P4RuntimeClient
 	deviceID               uint64
 	isMaster               bool
-	electionID             v1.Uint128
+	electionID             *v1.Uint128
 	p4Info                 *configv1.P4Info
+	savedP4Info            *configv1.P4Info
 	IncomingMessageChannel chan *v1.StreamMessageResponse
 	OutgoingMessageChannel chan *v1.StreamMessageRequest
+	unsent                 *v1.StreamMessageRequest
 	streamChannel          v1.P4Runtime_StreamChannelClient
 	Entities               map[string]*(map[string]entity.Entity)
+	index                  *P4InfoIndex
+	state                  ConnectionState
+	stateMu                sync.RWMutex
+	stateChannel           chan ConnectionState
+	reconnectPolicy        ReconnectPolicy
+	timeout                time.Duration
+	timeoutSet             bool
+	role                   *Role
+	roleEntityIDs          map[uint32]bool
+	roleErr                error
+	conn                   *grpc.ClientConn
+	ctx                    context.Context
+	cancel                 context.CancelFunc
+	closeOnce              sync.Once
 }
 
-// Init 创建一个新的 gRPC 连接并初始化客户端。
-func (c *Client) Init(addr string, deviceID uint64, electionID v1.Uint128) error {
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+// DefaultTimeout 是客户端 RPC 的默认超时时间
+const DefaultTimeout = 30 * time.Second
+
+// SetDefaultTimeout 设置 RPC 的默认超时时间，0 表示不设置超时。
+// 默认超时只作用于没有截止时间的 context，包括不带 context 的方法。
+// Read 是服务端流，读取大表可能超过任何固定时长，因此不使用默认超时，需要时通过 ctx 设置。
+// 在 Init 之前调用时 Init 会保留该设置，没有设置时 Init 使用 DefaultTimeout；使用 NewClient 时可以通过 WithDefaultTimeout 设置。
+func (c *Client) SetDefaultTimeout(timeout time.Duration) {
+	c.timeout = timeout
+	c.timeoutSet = true
+}
+
+// withTimeout 在 ctx 没有截止时间时为其加上默认超时
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// Init 创建一个新的 gRPC 连接并初始化客户端，opts 用于配置 TLS 等连接参数。
+// electionID 使用指针是因为 v1.Uint128 是 protobuf 消息，内部带有不能复制的状态（go vet copylocks 会报告按值传递），
+// 客户端会复制一份保存，调用者之后修改传入的消息不会产生影响。
+func (c *Client) Init(addr string, deviceID uint64, electionID *v1.Uint128, opts ...Option) error {
+	dc, err := newDialConfig(opts)
 	if err != nil {
 		return err
 	}
+	conn, err := grpc.Dial(addr, dc.grpcOptions()...)
+	if err != nil {
+		return err
+	}
+
+	if dc.timeout != nil {
+		c.SetDefaultTimeout(*dc.timeout)
+	} else if !c.timeoutSet {
+		c.timeout = DefaultTimeout
+	}
+	ctx, cancel := c.withTimeout(context.Background())
+	defer cancel()
 
 	p4RtC := v1.NewP4RuntimeClient(conn)
-	resp, err := p4RtC.Capabilities(context.Background(), &v1.CapabilitiesRequest{})
+	resp, err := p4RtC.Capabilities(ctx, &v1.CapabilitiesRequest{})
 	if err != nil {
-		log.Fatal("Error in capabilities RPC", err)
+		conn.Close()
+		return fmt.Errorf("error in capabilities RPC: %v", err)
 	}
 	log.Println("P4Runtime server version is", resp.P4RuntimeApiVersion)
 
 	streamMsgs := make(chan *v1.StreamMessageResponse, 20)
-	pushMsgs := make(chan *v1.StreamMessageRequest)
+	pushMsgs := make(chan *v1.StreamMessageRequest, OutgoingBufferSize)
 
 	c.P4RuntimeClient = p4RtC
+	c.conn = conn
+	c.ctx, c.cancel = context.WithCancel(context.Background())
 	c.deviceID = deviceID
-	c.electionID = electionID
+	c.electionID = cloneElectionID(electionID)
+	c.role = dc.role
 	c.IncomingMessageChannel = streamMsgs
 	c.OutgoingMessageChannel = pushMsgs
-
-	stream, streamInitErr := c.StreamChannel(context.Background())
-	if streamInitErr != nil {
-		return streamInitErr
-	}
-
-	c.streamChannel = stream
+	c.state = Disconnected
+	c.stateChannel = make(chan ConnectionState, 10)
+	c.reconnectPolicy = DefaultReconnectPolicy
 
 	return nil
 }
@@ -76,66 +138,137 @@ func (c *Client) Run() {
 
 // WriteUpdate 用于更新交换机上的entity
 func (c *Client) WriteUpdate(update *v1.Update) error {
+	return c.WriteUpdateContext(context.Background(), update)
+}
+
+// WriteUpdateContext 与 WriteUpdate 相同，但使用 ctx 控制截止时间和取消
+func (c *Client) WriteUpdateContext(ctx context.Context, update *v1.Update) error {
+	return c.WriteUpdatesContext(ctx, []*v1.Update{update}, v1.WriteRequest_CONTINUE_ON_ERROR)
+}
+
+// WriteUpdates 在一个 WriteRequest 中发送多个 update，并使用指定的原子性模式
+func (c *Client) WriteUpdates(updates []*v1.Update, atomicity v1.WriteRequest_Atomicity) error {
+	return c.WriteUpdatesContext(context.Background(), updates, atomicity)
+}
+
+// WriteUpdatesContext 与 WriteUpdates 相同，但使用 ctx 控制截止时间和取消
+func (c *Client) WriteUpdatesContext(ctx context.Context, updates []*v1.Update, atomicity v1.WriteRequest_Atomicity) error {
 	req := &v1.WriteRequest{
 		DeviceId:   c.deviceID,
-		ElectionId: &c.electionID,
-		Updates:    []*v1.Update{update},
+		ElectionId: c.ElectionID(),
+		Role:       c.Role().name(),
+		Updates:    updates,
+		Atomicity:  atomicity,
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	if _, err := c.Write(ctx, req); err != nil {
+		return newWriteError(err, req.Updates)
 	}
+	return nil
+}
 
-	_, err := c.Write(context.Background(), req)
-	return err
+// NewBatch 创建一个通过该客户端发送的批次
+func (c *Client) NewBatch(atomicity v1.WriteRequest_Atomicity) *Batch {
+	return NewBatch(c, atomicity)
 }
 
-// ReadEntities 返回一个通道，通过该通道接收请求返回的所有实体
+// ReadEntities 返回一个通道，通过该通道接收请求返回的所有实体。
+// 读取因错误中断时通道同样会被关闭，错误只记录日志，需要获取错误时使用 ReadEntitiesContext。
 func (c *Client) ReadEntities(entities []*v1.Entity) (chan *v1.Entity, error) {
+	entityChannel, errChannel, err := c.ReadEntitiesContext(context.Background(), entities)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		if err := <-errChannel; err != nil {
+			log.Println("Read stream ended with error:", err)
+		}
+	}()
+	return entityChannel, nil
+}
+
+// ReadEntitiesContext 与 ReadEntities 相同，但使用 ctx 控制截止时间和取消。
+// 读取是服务端流，不使用默认超时。实体通道关闭后，错误通道会收到一个值：
+// 流正常结束时为 nil，否则为 Recv 返回的错误或 ctx 的错误。
+// 即使调用者不再从实体通道中读取，ctx 被取消后 goroutine 也会退出。
+func (c *Client) ReadEntitiesContext(ctx context.Context, entities []*v1.Entity) (chan *v1.Entity, <-chan error, error) {
 	req := &v1.ReadRequest{
 		DeviceId: c.deviceID,
+		Role:     c.Role().name(),
 		Entities: entities,
 	}
-	stream, err := c.Read(context.TODO(), req)
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.Read(ctx, req)
 	if err != nil {
-		return nil, err
+		cancel()
+		return nil, nil, err
 	}
 
 	entityChannel := make(chan *v1.Entity)
+	errChannel := make(chan error, 1)
 	go func() {
-		defer close(entityChannel)
-		for {
-			res, err := stream.Recv()
-			if err == io.EOF {
-				break
-			}
-			if err != nil {
-				break
-			}
-			for _, e := range res.Entities {
-				entityChannel <- e
-			}
-		}
+		defer cancel()
+		err := receiveEntities(ctx, stream, entityChannel)
+		close(entityChannel)
+		errChannel <- err
+		close(errChannel)
 	}()
 
-	return entityChannel, nil
+	return entityChannel, errChannel, nil
+}
+
+// receiveEntities 将流中的实体逐个发送到 entityChannel，流正常结束时返回 nil
+func receiveEntities(ctx context.Context, stream v1.P4Runtime_ReadClient, entityChannel chan<- *v1.Entity) error {
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, e := range res.Entities {
+			select {
+			case entityChannel <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
 }
 
 // ReadEntitiesSync 调用 ReadEntities，累积结果并一次性返回
 func (c *Client) ReadEntitiesSync(entities []*v1.Entity) ([]*v1.Entity, error) {
-	entityChannel, err := c.ReadEntities(entities)
+	return c.ReadEntitiesSyncContext(context.Background(), entities)
+}
+
+// ReadEntitiesSyncContext 与 ReadEntitiesSync 相同，但使用 ctx 控制截止时间和取消。
+// 读取中断时返回中断的原因，而不是不完整的结果。
+func (c *Client) ReadEntitiesSyncContext(ctx context.Context, entities []*v1.Entity) ([]*v1.Entity, error) {
+	entityChannel, errChannel, err := c.ReadEntitiesContext(ctx, entities)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]*v1.Entity, 1)
+	result := make([]*v1.Entity, 0)
 	for e := range entityChannel {
 		result = append(result, e)
 	}
+	if err := <-errChannel; err != nil {
+		return nil, err
+	}
 
 	return result, nil
 }
 
-// NewClient 创建一个新的 P4 Runtime 客户端
-func NewClient(addr string, deviceID uint64, electionID v1.Uint128) (P4RClient, error) {
+// NewClient 创建一个新的 P4 Runtime 客户端，opts 用于配置 TLS 等连接参数。
+// electionID 的说明见 Init。
+func NewClient(addr string, deviceID uint64, electionID *v1.Uint128, opts ...Option) (P4RClient, error) {
 	client := &Client{}
-	initErr := client.Init(addr, deviceID, electionID)
+	initErr := client.Init(addr, deviceID, electionID, opts...)
 	if initErr != nil {
 		return nil, initErr
 	}
@@ -156,63 +289,73 @@ func (c *Client) GetMessageChannels() MessageChannels {
 func (c *Client) GetArbitrationData() ArbitrationData {
 	return ArbitrationData{
 		DeviceID:   c.deviceID,
-		ElectionID: c.electionID,
+		ElectionID: c.ElectionID(),
+		Role:       c.Role().p4(),
 	}
 }
 
+// ElectionID 返回客户端当前使用的选举 ID
+func (c *Client) ElectionID() *v1.Uint128 {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.electionID
+}
+
+// SetElectionID 修改客户端的选举 ID，之后的写请求和仲裁请求都会使用新的选举 ID。
+// 修改选举 ID 不会自动重新仲裁，需要重新发送 MasterArbitrationUpdate 才能让交换机更新主控权。
+func (c *Client) SetElectionID(electionID *v1.Uint128) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.electionID = cloneElectionID(electionID)
+}
+
+// cloneElectionID 复制选举 ID，避免调用者之后修改传入的消息
+func cloneElectionID(electionID *v1.Uint128) *v1.Uint128 {
+	return &v1.Uint128{High: electionID.GetHigh(), Low: electionID.GetLow()}
+}
+
 func (c *Client) GetStreamChannel() v1.P4Runtime_StreamChannelClient {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.streamChannel
 }
 
 func (c *Client) P4Info() *configv1.P4Info {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.p4Info
 }
 
 func (c *Client) IsMaster() bool {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.isMaster
 }
 
 func (c *Client) SetMastershipStatus(status bool) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
 	c.isMaster = status
 }
 
 func (c *Client) GetEntities(EntityType string) *map[string]entity.Entity {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
 	return c.Entities[EntityType]
 }
 
-// StartMessageChannels 启动两个 goroutine，
-// 一个监听流通道并将接收到的消息发送到 IncomingMessageChannel
-// 另一个监听 OutgoingMessageChannel 并将消息发送到 gRPC 流通道
-func (c *Client) StartMessageChannels() {
-	stream, err := c.StreamChannel(context.Background())
-	if err != nil {
-		log.Fatal("Unable to get StreamChannel for client")
-	}
-
-	// 接收消息的 goroutine
-	go func() {
-		for {
-			in, err := stream.Recv()
-			if err == io.EOF {
-				log.Println("Failed to get message from stream:", err)
-			}
-			if err != nil {
-				log.Println("Error receiving message from stream:", err)
-			}
-
-			c.IncomingMessageChannel <- in
-		}
-	}()
+// Index 返回当前 P4Info 的索引，尚未加载 P4Info 时返回 nil，nil 索引的查找总是返回 ErrPipelineNotInstalled
+func (c *Client) Index() *P4InfoIndex {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.index
+}
 
-	// 发送消息的 goroutine
-	go func() {
-		for {
-			sendMess := <-c.OutgoingMessageChannel
-			if err := stream.Send(sendMess); err != nil {
-				log.Println("Unable to send message to stream")
-			}
-		}
-	}()
+// StartMessageChannels 启动一个 goroutine 维护 StreamChannel：
+// 将流上接收到的消息发送到 IncomingMessageChannel，将 OutgoingMessageChannel 中的消息发送到流上，
+// 并在流断开后按 ReconnectPolicy 自动重连，直到调用 Close。
+func (c *Client) StartMessageChannels() {
+	go c.superviseStream()
 }
 
 // getDeviceConfig 读取二进制设备配置文件
@@ -220,29 +363,43 @@ func getDeviceConfig(binPath string) ([]byte, error) {
 	return ioutil.ReadFile(binPath)
 }
 
-// SetFwdPipe 在目标设备上安装 P4 编译的二进制文件
-func (c *Client) SetFwdPipe(binPath string, p4infoPath string) error {
-	deviceConfig, err := getDeviceConfig(binPath)
+// SetFwdPipe 在目标设备上安装 P4 编译的二进制文件，opts 用于指定安装模式和 cookie，默认为 VERIFY_AND_COMMIT
+func (c *Client) SetFwdPipe(binPath string, p4infoPath string, opts ...FwdPipeOption) error {
+	return c.SetFwdPipeContext(context.Background(), binPath, p4infoPath, opts...)
+}
+
+// SetFwdPipeContext 与 SetFwdPipe 相同，但使用 ctx 控制截止时间和取消
+func (c *Client) SetFwdPipeContext(ctx context.Context, binPath string, p4infoPath string, opts ...FwdPipeOption) error {
+	config := newFwdPipeConfig(opts)
+	deviceConfig, p4Info, err := loadPipeline(binPath, p4infoPath, config)
 	if err != nil {
-		return fmt.Errorf("error when reading binary device config: %v", err)
+		return err
 	}
-	p4Info, err := getP4Info(p4infoPath)
+	return c.setFwdPipe(ctx, deviceConfig, p4Info, config)
+}
+
+// SetFwdPipeConfig 与 SetFwdPipeContext 相同，但使用内存中的设备配置和已经解析的 P4Info，
+// 内存中的 P4Info 可以通过 ParseP4Info 解析
+func (c *Client) SetFwdPipeConfig(ctx context.Context, deviceConfig []byte, p4Info *configv1.P4Info, opts ...FwdPipeOption) error {
+	return c.setFwdPipe(ctx, deviceConfig, p4Info, newFwdPipeConfig(opts))
+}
+
+// loadPipeline 读取设备配置文件和 P4Info 文件，P4Info 的格式由 WithP4InfoFormat 指定
+func loadPipeline(binPath string, p4infoPath string, config *fwdPipeConfig) ([]byte, *configv1.P4Info, error) {
+	deviceConfig, err := getDeviceConfig(binPath)
 	if err != nil {
-		return fmt.Errorf("error when reading P4Info text file: %v", err)
+		return nil, nil, fmt.Errorf("error when reading binary device config: %v", err)
 	}
-	config := &v1.ForwardingPipelineConfig{
-		P4Info:         p4Info,
-		P4DeviceConfig: deviceConfig,
-	}
-	req := &v1.SetForwardingPipelineConfigRequest{
-		DeviceId:   c.deviceID,
-		ElectionId: &c.electionID,
-		Action:     v1.SetForwardingPipelineConfigRequest_VERIFY_AND_COMMIT,
-		Config:     config,
+	p4Info, err := LoadP4Info(p4infoPath, config.p4InfoFormat)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error when reading P4Info file: %v", err)
 	}
-	_, err = c.SetForwardingPipelineConfig(context.Background(), req)
+	return deviceConfig, p4Info, nil
+}
 
-	// 设置client的entity
+// setP4Info 保存 P4Info 并根据它设置 client 的 entity。
+// 实体和索引在加锁前构建，加锁后一起替换，读取者总是看到同一个 P4Info 的实体、索引和角色。
+func (c *Client) setP4Info(p4Info *configv1.P4Info) {
 	Tables := make(map[string]entity.Entity)
 	for _, table := range p4Info.Tables {
 		t := entity.GetTable(table)
@@ -257,7 +414,7 @@ func (c *Client) SetFwdPipe(binPath string, p4infoPath string) error {
 
 	Digests := make(map[string]entity.Entity)
 	for _, digest := range p4Info.Digests {
-		d := entity.GetDigest(digest)
+		d := entity.GetDigest(digest, p4Info.TypeInfo)
 		Digests[digest.Preamble.Name] = entity.Entity(&d)
 	}
 
@@ -267,58 +424,49 @@ func (c *Client) SetFwdPipe(binPath string, p4infoPath string) error {
 		Counters[counter.Preamble.Name] = entity.Entity(&co)
 	}
 
-	Entities := make(map[string]*map[string]entity.Entity)
-	Entities["TABLE"] = &Tables
-	Entities["ACTION"] = &Actions
-	Entities["DIGEST"] = &Digests
-	Entities["COUNTER"] = &Counters
-	c.Entities = Entities
-
-	if err == nil {
-		c.p4Info = p4Info
+	Meters := make(map[string]entity.Entity)
+	for _, meter := range p4Info.Meters {
+		m := entity.GetMeter(meter)
+		Meters[meter.Preamble.Name] = entity.Entity(&m)
 	}
-	return err
-}
-
-const invalidID = 0
-
-// getP4Info 读取 P4Info 文本文件
-func getP4Info(p4InfoPath string) (*configv1.P4Info, error) {
-	bytes, err := ioutil.ReadFile(p4InfoPath)
-	if err != nil {
-		return nil, err
+	for _, meter := range p4Info.DirectMeters {
+		m := entity.GetDirectMeter(meter)
+		Meters[meter.Preamble.Name] = entity.Entity(&m)
 	}
 
-	p4Info := &configv1.P4Info{}
-	if err = proto.UnmarshalText(string(bytes), p4Info); err != nil {
-		return nil, err
+	Registers := make(map[string]entity.Entity)
+	for _, register := range p4Info.Registers {
+		r := entity.GetRegister(register, p4Info.TypeInfo)
+		Registers[register.Preamble.Name] = entity.Entity(&r)
 	}
 
-	return p4Info, nil
-}
-
-func getTableID(p4Info *configv1.P4Info, name string) uint32 {
-	if p4Info == nil {
-		return invalidID
-	}
-	for _, table := range p4Info.Tables {
-		if table.Preamble.Name == name {
-			return table.Preamble.Id
-		}
+	ActionProfiles := make(map[string]entity.Entity)
+	for _, actionProfile := range p4Info.ActionProfiles {
+		ap := entity.GetActionProfile(actionProfile)
+		ActionProfiles[actionProfile.Preamble.Name] = entity.Entity(&ap)
 	}
 
-	return invalidID
-}
-
-func getActionID(p4Info *configv1.P4Info, name string) uint32 {
-	if p4Info == nil {
-		return invalidID
-	}
-	for _, action := range p4Info.Actions {
-		if action.Preamble.Name == name {
-			return action.Preamble.Id
-		}
+	PacketMetadata := make(map[string]entity.Entity)
+	for _, cpm := range p4Info.ControllerPacketMetadata {
+		m := entity.GetControllerPacketMetadata(cpm)
+		PacketMetadata[cpm.Preamble.Name] = entity.Entity(&m)
 	}
 
-	return invalidID
+	Entities := make(map[string]*map[string]entity.Entity)
+	Entities["TABLE"] = &Tables
+	Entities["ACTION"] = &Actions
+	Entities["DIGEST"] = &Digests
+	Entities["COUNTER"] = &Counters
+	Entities["METER"] = &Meters
+	Entities["REGISTER"] = &Registers
+	Entities["ACTION_PROFILE"] = &ActionProfiles
+	Entities["CONTROLLER_PACKET_METADATA"] = &PacketMetadata
+	index := newP4InfoIndex(p4Info, Entities)
+
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	c.Entities = Entities
+	c.index = index
+	c.p4Info = p4Info
+	c.resolveRoleLocked()
 }
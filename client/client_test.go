@@ -0,0 +1,170 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeReadStream 依次返回 responses 中的响应，之后返回 err
+type fakeReadStream struct {
+	v1.P4Runtime_ReadClient
+	responses []*v1.ReadResponse
+	err       error
+}
+
+func (s *fakeReadStream) Recv() (*v1.ReadResponse, error) {
+	if len(s.responses) == 0 {
+		return nil, s.err
+	}
+	res := s.responses[0]
+	s.responses = s.responses[1:]
+	return res, nil
+}
+
+// fakeReader 记录 Read 请求的 ctx，并返回 stream
+type fakeReader struct {
+	v1.P4RuntimeClient
+	stream *fakeReadStream
+	ctx    context.Context
+}
+
+func (f *fakeReader) Read(ctx context.Context, _ *v1.ReadRequest, _ ...grpc.CallOption) (v1.P4Runtime_ReadClient, error) {
+	f.ctx = ctx
+	return f.stream, nil
+}
+
+func TestReadEntitiesSyncContext(t *testing.T) {
+	response := &v1.ReadResponse{Entities: []*v1.Entity{{}, {}}}
+	streamErr := errors.New("stream reset")
+
+	tests := []struct {
+		name      string
+		responses []*v1.ReadResponse
+		err       error
+		wantCount int
+		wantErr   error
+	}{
+		{"end of stream", []*v1.ReadResponse{response, response}, io.EOF, 4, nil},
+		{"empty stream", nil, io.EOF, 0, nil},
+		{"recv error after partial read", []*v1.ReadResponse{response}, streamErr, 0, streamErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &fakeReader{stream: &fakeReadStream{responses: tt.responses, err: tt.err}}
+			c := &Client{P4RuntimeClient: reader, timeout: DefaultTimeout}
+
+			res, err := c.ReadEntitiesSyncContext(context.Background(), nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ReadEntitiesSyncContext() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(res) != tt.wantCount {
+				t.Errorf("ReadEntitiesSyncContext() returned %d entities, want %d", len(res), tt.wantCount)
+			}
+			if _, ok := reader.ctx.Deadline(); ok {
+				t.Error("Read stream has a deadline, want none")
+			}
+		})
+	}
+}
+
+func TestReadEntitiesContextErrorChannel(t *testing.T) {
+	streamErr := errors.New("stream reset")
+	reader := &fakeReader{stream: &fakeReadStream{
+		responses: []*v1.ReadResponse{{Entities: []*v1.Entity{{}}}},
+		err:       streamErr,
+	}}
+	c := &Client{P4RuntimeClient: reader}
+
+	entityChannel, errChannel, err := c.ReadEntitiesContext(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for range entityChannel {
+		count++
+	}
+	if count != 1 {
+		t.Errorf("received %d entities, want 1", count)
+	}
+	if err := <-errChannel; !errors.Is(err, streamErr) {
+		t.Errorf("error channel yielded %v, want %v", err, streamErr)
+	}
+}
+
+// trackedConn 记录连接是否已经关闭
+type trackedConn struct {
+	net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+func TestInitTimeoutAndCapabilitiesError(t *testing.T) {
+	tests := []struct {
+		name        string
+		preset      *time.Duration
+		opts        []Option
+		wantTimeout time.Duration
+	}{
+		{"option", nil, []Option{WithDefaultTimeout(50 * time.Millisecond)}, 50 * time.Millisecond},
+		{"set before Init", durationPtr(60 * time.Millisecond), nil, 60 * time.Millisecond},
+		{"option overrides earlier setting", durationPtr(time.Hour), []Option{WithDefaultTimeout(70 * time.Millisecond)}, 70 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 对端不响应 HTTP/2，Capabilities 会在默认超时后失败
+			var mu sync.Mutex
+			var dialed []*trackedConn
+			dialer := grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+				local, remote := net.Pipe()
+				go io.Copy(io.Discard, remote)
+				conn := &trackedConn{Conn: local, closed: make(chan struct{})}
+				mu.Lock()
+				dialed = append(dialed, conn)
+				mu.Unlock()
+				return conn, nil
+			})
+
+			c := &Client{}
+			if tt.preset != nil {
+				c.SetDefaultTimeout(*tt.preset)
+			}
+			opts := append([]Option{WithDialOptions(dialer)}, tt.opts...)
+			if err := c.Init("passthrough:///switch", 1, &v1.Uint128{Low: 1}, opts...); err == nil {
+				t.Fatal("Init() succeeded without a P4Runtime server")
+			}
+			if c.timeout != tt.wantTimeout {
+				t.Errorf("timeout = %v, want %v", c.timeout, tt.wantTimeout)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if len(dialed) == 0 {
+				t.Fatal("Init() did not dial the switch")
+			}
+			for _, conn := range dialed {
+				select {
+				case <-conn.closed:
+				case <-time.After(time.Second):
+					t.Fatal("Init() did not close the connection after Capabilities failed")
+				}
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
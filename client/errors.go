@@ -0,0 +1,108 @@
+package client
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UpdateError 描述 WriteRequest 中单个 update 的执行结果，对应 P4Runtime 的 p4.v1.Error：
+//   - Update：对应的原始 update。
+//   - CanonicalCode：gRPC 规范错误码，执行成功的 update 为 codes.OK。
+//   - Message：错误描述。
+//   - Space：Code 所属的错误空间，由目标设备定义。
+//   - Code：目标设备定义的错误码。
+type UpdateError struct {
+	Update        *v1.Update
+	CanonicalCode codes.Code
+	Message       string
+	Space         string
+	Code          int32
+}
+
+func (e *UpdateError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Update.GetType(), e.CanonicalCode, e.Message)
+}
+
+// WriteError 是 Write RPC 失败时返回的错误。P4Runtime 会在 google.rpc.Status 的 details 中
+// 为每个 update 按顺序放入一个 p4.v1.Error，WriteError 将其解包为 Updates，
+// 调用者可以据此得知具体哪些 update 失败并只重试这些 update。
+type WriteError struct {
+	// Status 为 Write RPC 返回的 gRPC 状态
+	Status *status.Status
+	// Updates 与请求中的 update 一一对应；若服务端没有返回逐条结果则为空
+	Updates []*UpdateError
+}
+
+func (e *WriteError) Error() string {
+	failed := e.Failed()
+	if len(failed) == 0 {
+		return fmt.Sprintf("write failed: %s", e.Status.Message())
+	}
+
+	messages := make([]string, 0, len(failed))
+	for _, f := range failed {
+		messages = append(messages, f.Error())
+	}
+	return fmt.Sprintf("write failed for %d of %d updates: %s",
+		len(failed), len(e.Updates), strings.Join(messages, "; "))
+}
+
+// Unwrap 返回原始的 gRPC 错误，便于使用 status.Code 等函数
+func (e *WriteError) Unwrap() error {
+	return e.Status.Err()
+}
+
+// Failed 返回所有执行失败的 update 结果
+func (e *WriteError) Failed() []*UpdateError {
+	failed := make([]*UpdateError, 0)
+	for _, u := range e.Updates {
+		if u.CanonicalCode != codes.OK {
+			failed = append(failed, u)
+		}
+	}
+	return failed
+}
+
+// FailedUpdates 返回所有执行失败的原始 update，可直接用于重试
+func (e *WriteError) FailedUpdates() []*v1.Update {
+	failed := e.Failed()
+	updates := make([]*v1.Update, 0, len(failed))
+	for _, f := range failed {
+		updates = append(updates, f.Update)
+	}
+	return updates
+}
+
+// newWriteError 将 Write RPC 返回的错误转换为 WriteError；非 gRPC 状态错误原样返回
+func newWriteError(err error, updates []*v1.Update) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	writeErr := &WriteError{Status: st}
+	details := st.Proto().GetDetails()
+	if len(details) != len(updates) {
+		return writeErr
+	}
+
+	for idx, detail := range details {
+		p4Err := &v1.Error{}
+		if err := detail.UnmarshalTo(p4Err); err != nil {
+			writeErr.Updates = nil
+			return writeErr
+		}
+		writeErr.Updates = append(writeErr.Updates, &UpdateError{
+			Update:        updates[idx],
+			CanonicalCode: codes.Code(p4Err.CanonicalCode),
+			Message:       p4Err.Message,
+			Space:         p4Err.Space,
+			Code:          p4Err.Code,
+		})
+	}
+	return writeErr
+}
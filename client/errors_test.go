@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// writeStatus 返回一个 details 中带有 p4.v1.Error 的 gRPC 错误，每个错误码对应一个 update
+func writeStatus(t *testing.T, codeList ...codes.Code) error {
+	t.Helper()
+	st := status.New(codes.Unknown, "write failed")
+	for _, code := range codeList {
+		p4Err := &v1.Error{CanonicalCode: int32(code), Message: code.String(), Space: "target", Code: int32(code) + 100}
+		var err error
+		if st, err = st.WithDetails(p4Err); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return st.Err()
+}
+
+func TestNewWriteError(t *testing.T) {
+	updates := []*v1.Update{testUpdate(1, 0), testUpdate(2, 0), testUpdate(3, 0)}
+	plain := errors.New("connection reset")
+	foreignDetail, err := status.New(codes.Unknown, "write failed").WithDetails(
+		&v1.Error{}, wrapperspb.String("not a p4.v1.Error"), &v1.Error{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		err         error
+		wantWrite   bool
+		wantUpdates int
+		wantFailed  []uint32
+	}{
+		{"mixed OK and failed updates", writeStatus(t, codes.OK, codes.AlreadyExists, codes.NotFound), true, 3, []uint32{2, 3}},
+		{"all updates succeeded", writeStatus(t, codes.OK, codes.OK, codes.OK), true, 3, nil},
+		{"fewer details than updates", writeStatus(t, codes.OK, codes.AlreadyExists), true, 0, nil},
+		{"no details", status.Error(codes.Unavailable, "switch is down"), true, 0, nil},
+		{"detail that is not a p4.v1.Error", foreignDetail.Err(), true, 0, nil},
+		{"non-status error", plain, false, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newWriteError(tt.err, updates)
+
+			var writeErr *WriteError
+			if !errors.As(got, &writeErr) {
+				if tt.wantWrite {
+					t.Fatalf("newWriteError() = %v, want a *WriteError", got)
+				}
+				if got != tt.err {
+					t.Errorf("newWriteError() = %v, want the original error", got)
+				}
+				return
+			}
+			if !tt.wantWrite {
+				t.Fatalf("newWriteError() = %v, want the original error", got)
+			}
+			if status.Code(got) != status.Code(tt.err) {
+				t.Errorf("status.Code() = %v, want %v", status.Code(got), status.Code(tt.err))
+			}
+			if len(writeErr.Updates) != tt.wantUpdates {
+				t.Fatalf("newWriteError() has %d update results, want %d", len(writeErr.Updates), tt.wantUpdates)
+			}
+
+			failed := writeErr.FailedUpdates()
+			if len(failed) != len(tt.wantFailed) || len(writeErr.Failed()) != len(tt.wantFailed) {
+				t.Fatalf("FailedUpdates() returned %d updates, want %d", len(failed), len(tt.wantFailed))
+			}
+			for i, u := range failed {
+				if id := u.GetEntity().GetTableEntry().GetTableId(); id != tt.wantFailed[i] {
+					t.Errorf("FailedUpdates()[%d] is table %d, want %d", i, id, tt.wantFailed[i])
+				}
+			}
+			for i, f := range writeErr.Failed() {
+				if f.Update != failed[i] || f.Code != int32(f.CanonicalCode)+100 || f.Space != "target" {
+					t.Errorf("Failed()[%d] = %+v does not match its p4.v1.Error", i, f)
+				}
+			}
+		})
+	}
+}
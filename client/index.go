@@ -0,0 +1,119 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"p4r/entity"
+)
+
+var (
+	// ErrUnknownEntity 表示 P4Info 中没有对应名称、别名或 ID 的实体
+	ErrUnknownEntity = errors.New("unknown entity")
+	// ErrAmbiguousAlias 表示同一类型中有多个实体使用了相同的别名，需要使用完整名称
+	ErrAmbiguousAlias = errors.New("ambiguous alias")
+	// ErrPipelineNotInstalled 表示客户端还没有 P4Info，需要先安装程序或从交换机读取已安装的程序
+	ErrPipelineNotInstalled = errors.New("forwarding pipeline is not installed")
+)
+
+// P4InfoIndex 按完整名称、别名和 ID 索引 P4Info 中的实体，
+// 包括表、动作、计数器、meter、寄存器、digest、action profile 和 controller packet metadata。
+// 索引中的实体与 Client.GetEntities 返回的是同一个对象。
+type P4InfoIndex struct {
+	byName  map[string]map[string]entity.Entity
+	byAlias map[string]map[string][]entity.Entity
+	byID    map[uint32]entity.Entity
+}
+
+// newP4InfoIndex 根据 P4Info 中的 preamble 为 entities 中已经创建的实体建立索引
+func newP4InfoIndex(p4Info *configv1.P4Info, entities map[string]*map[string]entity.Entity) *P4InfoIndex {
+	idx := &P4InfoIndex{
+		byName:  make(map[string]map[string]entity.Entity),
+		byAlias: make(map[string]map[string][]entity.Entity),
+		byID:    make(map[uint32]entity.Entity),
+	}
+
+	add := func(entityType string, preamble *configv1.Preamble) {
+		byType, ok := entities[entityType]
+		if !ok {
+			return
+		}
+		e, ok := (*byType)[preamble.GetName()]
+		if !ok {
+			return
+		}
+
+		if idx.byName[entityType] == nil {
+			idx.byName[entityType] = make(map[string]entity.Entity)
+			idx.byAlias[entityType] = make(map[string][]entity.Entity)
+		}
+		idx.byName[entityType][preamble.GetName()] = e
+		if alias := preamble.GetAlias(); alias != "" {
+			idx.byAlias[entityType][alias] = append(idx.byAlias[entityType][alias], e)
+		}
+		idx.byID[preamble.GetId()] = e
+	}
+
+	for _, t := range p4Info.Tables {
+		add("TABLE", t.Preamble)
+	}
+	for _, a := range p4Info.Actions {
+		add("ACTION", a.Preamble)
+	}
+	for _, c := range p4Info.Counters {
+		add("COUNTER", c.Preamble)
+	}
+	for _, m := range p4Info.Meters {
+		add("METER", m.Preamble)
+	}
+	for _, m := range p4Info.DirectMeters {
+		add("METER", m.Preamble)
+	}
+	for _, r := range p4Info.Registers {
+		add("REGISTER", r.Preamble)
+	}
+	for _, d := range p4Info.Digests {
+		add("DIGEST", d.Preamble)
+	}
+	for _, ap := range p4Info.ActionProfiles {
+		add("ACTION_PROFILE", ap.Preamble)
+	}
+	for _, cpm := range p4Info.ControllerPacketMetadata {
+		add("CONTROLLER_PACKET_METADATA", cpm.Preamble)
+	}
+	return idx
+}
+
+// Lookup 按名称查找指定类型的实体，name 可以是完整名称或别名，完整名称优先。
+// 同一类型中有多个实体使用该别名时返回 ErrAmbiguousAlias，找不到时返回 ErrUnknownEntity。
+func (idx *P4InfoIndex) Lookup(entityType, name string) (entity.Entity, error) {
+	if idx == nil {
+		return nil, fmt.Errorf("%w: cannot look up %s %s", ErrPipelineNotInstalled, entityType, name)
+	}
+	if e, ok := idx.byName[entityType][name]; ok {
+		return e, nil
+	}
+
+	candidates := idx.byAlias[entityType][name]
+	switch len(candidates) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s %s", ErrUnknownEntity, entityType, name)
+	case 1:
+		return candidates[0], nil
+	default:
+		return nil, fmt.Errorf("%w: %s alias %s matches %d entities", ErrAmbiguousAlias, entityType, name, len(candidates))
+	}
+}
+
+// LookupID 按 ID 查找实体，P4Info 中的 ID 在所有类型之间唯一
+func (idx *P4InfoIndex) LookupID(id uint32) (entity.Entity, error) {
+	if idx == nil {
+		return nil, fmt.Errorf("%w: cannot look up ID %d", ErrPipelineNotInstalled, id)
+	}
+	e, ok := idx.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: ID %d", ErrUnknownEntity, id)
+	}
+	return e, nil
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+)
+
+func preamble(id uint32, name, alias string) *configv1.Preamble {
+	return &configv1.Preamble{Id: id, Name: name, Alias: alias}
+}
+
+// testIndex 返回一个通过 setP4Info 建立的索引，其中：
+//   - 两个表使用相同的别名 forward；
+//   - 表和动作使用相同的别名 drop，但类型不同；
+//   - 表 ingress.acl 的别名 ingress.nat 与另一个表的完整名称相同。
+func testIndex() *P4InfoIndex {
+	c := &Client{}
+	c.setP4Info(&configv1.P4Info{
+		Tables: []*configv1.Table{
+			{Preamble: preamble(1, "ingress.forward", "forward")},
+			{Preamble: preamble(2, "egress.forward", "forward")},
+			{Preamble: preamble(3, "ingress.drop", "drop")},
+			{Preamble: preamble(4, "ingress.nat", "nat")},
+			{Preamble: preamble(5, "ingress.acl", "ingress.nat")},
+			{Preamble: preamble(6, "ingress.unaliased", "")},
+		},
+		Actions: []*configv1.Action{
+			{Preamble: preamble(10, "ingress.drop_packet", "drop")},
+		},
+	})
+	return c.Index()
+}
+
+func TestP4InfoIndexLookup(t *testing.T) {
+	idx := testIndex()
+
+	tests := []struct {
+		name       string
+		entityType string
+		lookup     string
+		wantID     uint32
+		wantErr    error
+	}{
+		{"full name", "TABLE", "ingress.forward", 1, nil},
+		{"full name of a table sharing its alias", "TABLE", "egress.forward", 2, nil},
+		{"ambiguous alias", "TABLE", "forward", 0, ErrAmbiguousAlias},
+		{"unique alias", "TABLE", "nat", 4, nil},
+		{"alias shared with another type", "TABLE", "drop", 3, nil},
+		{"alias shared with another type as action", "ACTION", "drop", 10, nil},
+		{"full name wins over alias", "TABLE", "ingress.nat", 4, nil},
+		{"empty alias is not indexed", "TABLE", "", 0, ErrUnknownEntity},
+		{"unknown name", "TABLE", "missing", 0, ErrUnknownEntity},
+		{"name of another type", "ACTION", "ingress.forward", 0, ErrUnknownEntity},
+		{"unknown type", "COUNTER", "forward", 0, ErrUnknownEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := idx.Lookup(tt.entityType, tt.lookup)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup(%q, %q) error = %v, want %v", tt.entityType, tt.lookup, err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if e.GetID() != tt.wantID {
+				t.Errorf("Lookup(%q, %q) = ID %d, want %d", tt.entityType, tt.lookup, e.GetID(), tt.wantID)
+			}
+			if e.Type() != tt.entityType {
+				t.Errorf("Lookup(%q, %q) = type %s, want %s", tt.entityType, tt.lookup, e.Type(), tt.entityType)
+			}
+		})
+	}
+}
+
+func TestP4InfoIndexLookupID(t *testing.T) {
+	idx := testIndex()
+
+	tests := []struct {
+		name     string
+		id       uint32
+		wantType string
+		wantErr  error
+	}{
+		{"table", 2, "TABLE", nil},
+		{"action", 10, "ACTION", nil},
+		{"unknown ID", 99, "", ErrUnknownEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := idx.LookupID(tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LookupID(%d) error = %v, want %v", tt.id, err, tt.wantErr)
+			}
+			if tt.wantErr == nil && e.Type() != tt.wantType {
+				t.Errorf("LookupID(%d) = type %s, want %s", tt.id, e.Type(), tt.wantType)
+			}
+		})
+	}
+}
+
+func TestP4InfoIndexNotInstalled(t *testing.T) {
+	var idx *P4InfoIndex
+	if _, err := idx.Lookup("TABLE", "forward"); !errors.Is(err, ErrPipelineNotInstalled) {
+		t.Errorf("Lookup() error = %v, want %v", err, ErrPipelineNotInstalled)
+	}
+	if _, err := idx.LookupID(1); !errors.Is(err, ErrPipelineNotInstalled) {
+		t.Errorf("LookupID() error = %v, want %v", err, ErrPipelineNotInstalled)
+	}
+}
+
+// TestSetP4InfoConcurrentReads 在加载 P4Info 的同时读取实体和索引，用 go test -race 检查数据竞争
+func TestSetP4InfoConcurrentReads(t *testing.T) {
+	p4Infos := []*configv1.P4Info{
+		{Tables: []*configv1.Table{{Preamble: preamble(1, "ingress.forward", "forward")}}},
+		{Tables: []*configv1.Table{{Preamble: preamble(2, "ingress.acl", "acl")}}},
+	}
+	c := &Client{}
+	c.setP4Info(p4Infos[0])
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.setP4Info(p4Infos[i%2])
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if c.P4Info() == nil || c.GetEntities("TABLE") == nil {
+			t.Fatal("P4Info() or GetEntities() returned nil while a P4Info was being replaced")
+		}
+		if _, err := c.Index().Lookup("TABLE", c.P4Info().Tables[0].Preamble.Alias); err != nil && !errors.Is(err, ErrUnknownEntity) {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Option 是 NewClient 和 Init 的可选配置，例如 TLS 证书、额外的 grpc.DialOption 和角色。
+// 没有设置任何 TLS 相关选项时使用不加密的连接。
+type Option func(*dialConfig) error
+
+// dialConfig 保存建立 gRPC 连接所需的配置：
+//   - tlsConfig：TLS 配置，为 nil 时不使用 TLS。
+//   - dialOptions：调用者提供的额外 grpc.DialOption。
+//   - role：客户端的角色，为 nil 时使用默认角色。
+//   - timeout：RPC 的默认超时时间，为 nil 时不修改客户端已经设置的超时时间。
+type dialConfig struct {
+	tlsConfig   *tls.Config
+	dialOptions []grpc.DialOption
+	role        *Role
+	timeout     *time.Duration
+}
+
+// tls 返回 TLS 配置，不存在时创建一个使用系统根证书的配置
+func (dc *dialConfig) tls() *tls.Config {
+	if dc.tlsConfig == nil {
+		dc.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return dc.tlsConfig
+}
+
+// grpcOptions 根据配置生成 grpc.Dial 使用的选项
+func (dc *dialConfig) grpcOptions() []grpc.DialOption {
+	var creds credentials.TransportCredentials
+	if dc.tlsConfig != nil {
+		creds = credentials.NewTLS(dc.tlsConfig)
+	} else {
+		creds = insecure.NewCredentials()
+	}
+	return append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, dc.dialOptions...)
+}
+
+func newDialConfig(opts []Option) (*dialConfig, error) {
+	dc := &dialConfig{}
+	for _, opt := range opts {
+		if err := opt(dc); err != nil {
+			return nil, err
+		}
+	}
+	return dc, nil
+}
+
+// WithTLS 使用 TLS 连接交换机，并使用系统根证书验证交换机证书
+func WithTLS() Option {
+	return func(dc *dialConfig) error {
+		dc.tls()
+		return nil
+	}
+}
+
+// WithCACertPEM 使用 PEM 格式的 CA 证书验证交换机证书，同时启用 TLS
+func WithCACertPEM(pem []byte) Option {
+	return func(dc *dialConfig) error {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no valid CA certificate found in PEM data")
+		}
+		dc.tls().RootCAs = pool
+		return nil
+	}
+}
+
+// WithCACertFile 从文件中读取 PEM 格式的 CA 证书，见 WithCACertPEM
+func WithCACertFile(path string) Option {
+	return func(dc *dialConfig) error {
+		pem, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error when reading CA certificate: %v", err)
+		}
+		return WithCACertPEM(pem)(dc)
+	}
+}
+
+// WithClientCertPEM 使用 PEM 格式的客户端证书和私钥进行双向 TLS 认证，同时启用 TLS
+func WithClientCertPEM(certPEM, keyPEM []byte) Option {
+	return func(dc *dialConfig) error {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return fmt.Errorf("error when loading client certificate: %v", err)
+		}
+		dc.tls().Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// WithClientCertFile 从文件中读取 PEM 格式的客户端证书和私钥，见 WithClientCertPEM
+func WithClientCertFile(certFile, keyFile string) Option {
+	return func(dc *dialConfig) error {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return fmt.Errorf("error when loading client certificate: %v", err)
+		}
+		dc.tls().Certificates = []tls.Certificate{cert}
+		return nil
+	}
+}
+
+// WithServerName 覆盖验证交换机证书时使用的服务器名称，适用于通过 IP 地址连接的情况，同时启用 TLS
+func WithServerName(name string) Option {
+	return func(dc *dialConfig) error {
+		dc.tls().ServerName = name
+		return nil
+	}
+}
+
+// WithDialOptions 追加任意 grpc.DialOption，例如拦截器或 keepalive 参数
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(dc *dialConfig) error {
+		dc.dialOptions = append(dc.dialOptions, opts...)
+		return nil
+	}
+}
+
+// WithDefaultTimeout 设置 RPC 的默认超时时间，包括 Init 中的 Capabilities 请求，0 表示不设置超时，见 SetDefaultTimeout
+func WithDefaultTimeout(timeout time.Duration) Option {
+	return func(dc *dialConfig) error {
+		dc.timeout = &timeout
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// P4InfoFormat 是 P4Info 的编码格式
+type P4InfoFormat int
+
+const (
+	// P4InfoAuto 根据文件扩展名或内容自动判断格式
+	P4InfoAuto P4InfoFormat = iota
+	// P4InfoText 为 protobuf 文本格式，例如 p4c 生成的 .p4info.txt
+	P4InfoText
+	// P4InfoBinary 为 protobuf 二进制格式
+	P4InfoBinary
+	// P4InfoJSON 为 protobuf JSON 格式
+	P4InfoJSON
+)
+
+func (f P4InfoFormat) String() string {
+	switch f {
+	case P4InfoAuto:
+		return "auto"
+	case P4InfoText:
+		return "text"
+	case P4InfoBinary:
+		return "binary"
+	case P4InfoJSON:
+		return "JSON"
+	default:
+		return "unknown"
+	}
+}
+
+// LoadP4Info 读取 P4Info 文件，format 为 P4InfoAuto 时先根据扩展名判断格式，无法判断时根据内容判断
+func LoadP4Info(path string, format P4InfoFormat) (*configv1.P4Info, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if format == P4InfoAuto {
+		format = formatFromExtension(path)
+	}
+	return ParseP4Info(data, format)
+}
+
+// ParseP4Info 解析内存中的 P4Info，format 为 P4InfoAuto 时根据内容判断格式：
+// 不含控制字符的 UTF-8 数据中，第一个非空白字符为 '{' 的按 JSON 解析，其他按文本格式解析，剩下的按二进制解析。
+// 二进制数据也可能恰好满足前两个条件，因此按 JSON 或文本格式解析失败时会再尝试按二进制解析。
+func ParseP4Info(data []byte, format P4InfoFormat) (*configv1.P4Info, error) {
+	if format != P4InfoAuto {
+		return parseP4Info(data, format)
+	}
+
+	format = formatFromContent(data)
+	p4Info, err := parseP4Info(data, format)
+	if err != nil && format != P4InfoBinary {
+		if binary, binErr := parseP4Info(data, P4InfoBinary); binErr == nil && len(binary.ProtoReflect().GetUnknown()) == 0 {
+			return binary, nil
+		}
+	}
+	return p4Info, err
+}
+
+func parseP4Info(data []byte, format P4InfoFormat) (*configv1.P4Info, error) {
+	p4Info := &configv1.P4Info{}
+	var err error
+	switch format {
+	case P4InfoText:
+		err = prototext.Unmarshal(data, p4Info)
+	case P4InfoBinary:
+		err = proto.Unmarshal(data, p4Info)
+	case P4InfoJSON:
+		err = protojson.Unmarshal(data, p4Info)
+	default:
+		return nil, fmt.Errorf("unsupported P4Info format %d", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing P4Info as %s: %v", format, err)
+	}
+	return p4Info, nil
+}
+
+// formatFromExtension 根据常见的文件扩展名判断格式，无法判断时返回 P4InfoAuto
+func formatFromExtension(path string) P4InfoFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".txt", ".pbtxt", ".txtpb", ".textproto":
+		return P4InfoText
+	case ".pb", ".bin", ".binpb":
+		return P4InfoBinary
+	case ".json":
+		return P4InfoJSON
+	default:
+		return P4InfoAuto
+	}
+}
+
+// formatFromContent 根据内容判断格式。二进制数据的首字节可能是 '\n' 等空白字符，
+// 因此先根据完整的数据判断是否为文本，再去掉空白判断是否为 JSON。
+func formatFromContent(data []byte) P4InfoFormat {
+	if !utf8.Valid(data) || hasControlChars(data) {
+		return P4InfoBinary
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return P4InfoJSON
+	}
+	return P4InfoText
+}
+
+// hasControlChars 判断 data 中是否含有除空白字符外的控制字符，文本格式中不会出现这些字符
+func hasControlChars(data []byte) bool {
+	for _, b := range data {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' {
+			return true
+		}
+	}
+	return false
+}
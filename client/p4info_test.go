@@ -0,0 +1,101 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+)
+
+// p4InfoWithName 返回只有 pkg_info.name 的 P4Info，name 的长度决定二进制编码中的长度字节
+func p4InfoWithName(length int) *configv1.P4Info {
+	return &configv1.P4Info{PkgInfo: &configv1.PkgInfo{Name: strings.Repeat("a", length)}}
+}
+
+func mustMarshal(t *testing.T, p4Info *configv1.P4Info) []byte {
+	t.Helper()
+	data, err := proto.Marshal(p4Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseP4Info(t *testing.T) {
+	p4Info := &configv1.P4Info{
+		PkgInfo: &configv1.PkgInfo{Name: "basic", Arch: "v1model"},
+		Tables:  []*configv1.Table{{Preamble: &configv1.Preamble{Id: 1, Name: "ingress.ipv4_lpm", Alias: "ipv4_lpm"}}},
+	}
+	text, err := prototext.Marshal(p4Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	json, err := protojson.Marshal(p4Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := mustMarshal(t, p4Info)
+	// pkg_info 的长度为 123 (0x7B)，编码以 "\n{" 开头且不含其他控制字符
+	braceP4Info := p4InfoWithName(121)
+	brace := mustMarshal(t, braceP4Info)
+	// pkg_info 的长度为 32 (0x20)，编码的第二个字节为空格
+	spaceP4Info := p4InfoWithName(30)
+	space := mustMarshal(t, spaceP4Info)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		format  P4InfoFormat
+		want    *configv1.P4Info
+		wantErr bool
+	}{
+		{"auto text", text, P4InfoAuto, p4Info, false},
+		{"auto JSON", json, P4InfoAuto, p4Info, false},
+		{"auto JSON with leading whitespace", append([]byte("\n  "), json...), P4InfoAuto, p4Info, false},
+		{"auto binary", binary, P4InfoAuto, p4Info, false},
+		{"auto binary with brace length byte", brace, P4InfoAuto, braceP4Info, false},
+		{"auto binary with space length byte", space, P4InfoAuto, spaceP4Info, false},
+		{"explicit text", text, P4InfoText, p4Info, false},
+		{"explicit binary", binary, P4InfoBinary, p4Info, false},
+		{"explicit format mismatch", binary, P4InfoJSON, nil, true},
+		{"auto invalid text", []byte("pkg_info {"), P4InfoAuto, nil, true},
+		{"unsupported format", text, P4InfoFormat(42), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseP4Info(tt.data, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseP4Info() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !proto.Equal(got, tt.want) {
+				t.Errorf("ParseP4Info() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatFromContent(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want P4InfoFormat
+	}{
+		{"text", []byte("pkg_info {\n  arch: \"v1model\"\n}\n"), P4InfoText},
+		{"JSON", []byte(`{"pkgInfo": {"arch": "v1model"}}`), P4InfoJSON},
+		{"JSON with leading whitespace", []byte("\r\n\t{}"), P4InfoJSON},
+		{"binary", []byte{0x0a, 0x05, 0x0a, 0x03, 'a', 'b', 'c'}, P4InfoBinary},
+		{"invalid UTF-8", []byte{'{', 0xff}, P4InfoBinary},
+		// 无法与 JSON 区分的二进制数据，由 ParseP4Info 回退到二进制解析
+		{"binary that looks like JSON", append([]byte{0x0a, '{', 0x0a, 'y'}, strings.Repeat("a", 121)...), P4InfoJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatFromContent(tt.data); got != tt.want {
+				t.Errorf("formatFromContent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
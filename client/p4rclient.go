@@ -1,6 +1,9 @@
 package client
 
 import (
+	"context"
+	"time"
+
 	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
 	"github.com/p4lang/p4runtime/go/p4/v1"
 	"p4r/entity"
@@ -17,9 +20,11 @@ type MessageChannels struct {
 // ArbitrationData 结构体，包含两个字段：
 //   - DeviceID 用于标识客户端所连接的特定 P4 设备。
 //   - ElectionID 标识客户端在流控制中的主控权。
+//   - Role 为客户端的角色，默认角色为 nil。
 type ArbitrationData struct {
 	DeviceID   uint64
-	ElectionID v1.Uint128
+	ElectionID *v1.Uint128
+	Role       *v1.Role
 }
 
 // EntityClient defines any client that can interact with P4 switch entities such
@@ -27,12 +32,37 @@ type ArbitrationData struct {
 type EntityClient interface {
 	GetEntities(string) *map[string]entity.Entity
 
+	// Index returns the index of the current P4Info, used to look up entities by
+	// fully-qualified name, alias or ID
+	Index() *P4InfoIndex
+
 	// WriteUpdate is used to update an entity on the switch. Refer to the P4Runtime spec to know more.
 	WriteUpdate(update *v1.Update) error
 
+	// WriteUpdateContext is like WriteUpdate but honours the deadline and cancellation of ctx.
+	WriteUpdateContext(ctx context.Context, update *v1.Update) error
+
+	// WriteUpdates sends several updates in a single WriteRequest with the given atomicity.
+	WriteUpdates(updates []*v1.Update, atomicity v1.WriteRequest_Atomicity) error
+
+	// WriteUpdatesContext is like WriteUpdates but honours the deadline and cancellation of ctx.
+	WriteUpdatesContext(ctx context.Context, updates []*v1.Update, atomicity v1.WriteRequest_Atomicity) error
+
+	// NewBatch returns a Batch that accumulates updates and sends them through this client.
+	NewBatch(atomicity v1.WriteRequest_Atomicity) *Batch
+
 	ReadEntities(entities []*v1.Entity) (chan *v1.Entity, error)
 
+	// ReadEntitiesContext is like ReadEntities; the stream stops and the channel is
+	// closed when ctx is cancelled or its deadline expires. After the entity channel
+	// is closed the error channel yields nil on a clean end of stream, or the error
+	// that stopped the read.
+	ReadEntitiesContext(ctx context.Context, entities []*v1.Entity) (chan *v1.Entity, <-chan error, error)
+
 	ReadEntitiesSync(entities []*v1.Entity) ([]*v1.Entity, error)
+
+	// ReadEntitiesSyncContext is like ReadEntitiesSync but honours the deadline and cancellation of ctx.
+	ReadEntitiesSyncContext(ctx context.Context, entities []*v1.Entity) ([]*v1.Entity, error)
 }
 
 // P4RClient represents a p4Runtime client. Most methods are just getters since Go's
@@ -40,13 +70,55 @@ type EntityClient interface {
 type P4RClient interface {
 	EntityClient
 	// To initialize the client
-	Init(addr string, deviceID uint64, electionID v1.Uint128) error
+	Init(addr string, deviceID uint64, electionID *v1.Uint128, opts ...Option) error
 
 	// Run will do whatever is needed to ensure that the client is active
 	// once it is initialized.
 	Run()
 
-	SetFwdPipe(binPath string, p4InfoPath string) error
+	// SetFwdPipe installs the pipeline, VERIFY_AND_COMMIT with a content cookie unless opts say otherwise
+	SetFwdPipe(binPath string, p4InfoPath string, opts ...FwdPipeOption) error
+
+	// SetFwdPipeContext is like SetFwdPipe but honours the deadline and cancellation of ctx.
+	SetFwdPipeContext(ctx context.Context, binPath string, p4InfoPath string, opts ...FwdPipeOption) error
+
+	// SetFwdPipeConfig is like SetFwdPipeContext but takes an in-memory device config and an already parsed P4Info.
+	SetFwdPipeConfig(ctx context.Context, deviceConfig []byte, p4Info *configv1.P4Info, opts ...FwdPipeOption) error
+
+	// CommitFwdPipe realizes the config previously saved with VERIFY_AND_SAVE
+	CommitFwdPipe() error
+
+	// CommitFwdPipeContext is like CommitFwdPipe but honours the deadline and cancellation of ctx.
+	CommitFwdPipeContext(ctx context.Context) error
+
+	// GetFwdPipe reads the pipeline installed on the switch. When the response carries
+	// the P4Info, the client's P4Info and entities are populated from it.
+	GetFwdPipe(responseType v1.GetForwardingPipelineConfigRequest_ResponseType) (*v1.ForwardingPipelineConfig, error)
+
+	// GetFwdPipeContext is like GetFwdPipe but honours the deadline and cancellation of ctx.
+	GetFwdPipeContext(ctx context.Context, responseType v1.GetForwardingPipelineConfigRequest_ResponseType) (*v1.ForwardingPipelineConfig, error)
+
+	// EnsureFwdPipe installs the pipeline only if the cookie on the switch differs from
+	// the expected one and reports whether it did
+	EnsureFwdPipe(binPath string, p4InfoPath string, opts ...FwdPipeOption) (bool, error)
+
+	// EnsureFwdPipeContext is like EnsureFwdPipe but honours the deadline and cancellation of ctx.
+	EnsureFwdPipeContext(ctx context.Context, binPath string, p4InfoPath string, opts ...FwdPipeOption) (bool, error)
+
+	// EnsureFwdPipeConfig is like EnsureFwdPipeContext but takes an in-memory device config and an already parsed P4Info.
+	EnsureFwdPipeConfig(ctx context.Context, deviceConfig []byte, p4Info *configv1.P4Info, opts ...FwdPipeOption) (bool, error)
+
+	// SetDefaultTimeout sets the timeout applied to unary RPCs whose context has no
+	// deadline, 0 disables it. Read streams are not subject to it.
+	SetDefaultTimeout(timeout time.Duration)
+
+	// SendMessage queues a message to be sent on the StreamChannel. Messages queued
+	// while the stream is down are sent after reconnecting. It never blocks: it fails
+	// when the outgoing buffer is full or the client is closed.
+	SendMessage(message *v1.StreamMessageRequest) error
+
+	// Close stops maintaining the StreamChannel and closes the gRPC connection.
+	Close() error
 
 	// GetMessageChannels will return the message channels used by the client
 	GetMessageChannels() MessageChannels
@@ -66,4 +138,30 @@ type P4RClient interface {
 
 	// SetMastershipStatus sets the mastership status of the client
 	SetMastershipStatus(bool)
+
+	// ElectionID returns the election ID currently used by the client
+	ElectionID() *v1.Uint128
+
+	// SetElectionID changes the election ID used by subsequent writes and arbitration
+	// requests. It does not re-run arbitration by itself.
+	SetElectionID(electionID *v1.Uint128)
+
+	// Role returns the role of the client, nil for the default role
+	Role() *Role
+
+	// SetRole changes the role used by subsequent reads, writes and arbitration
+	// requests. It does not re-run arbitration by itself. It fails, keeping the
+	// previous role, when an entity name of the role cannot be resolved.
+	SetRole(role *Role) error
+
+	// RoleEntityIDs returns the IDs of the entities the role may write, nil when
+	// the role does not restrict entities.
+	RoleEntityIDs() (map[uint32]bool, error)
+
+	// ConnectionState returns the current state of the StreamChannel
+	ConnectionState() ConnectionState
+
+	// ConnectionStateChannel returns a channel that receives every change of the
+	// StreamChannel state, including reconnections
+	ConnectionStateChannel() <-chan ConnectionState
 }
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// FwdPipeOption 是 SetFwdPipe 的可选配置
+type FwdPipeOption func(*fwdPipeConfig)
+
+// fwdPipeConfig 保存 SetForwardingPipelineConfig 请求的参数：
+//   - action：安装模式。
+//   - cookie：流水线 cookie，为 nil 时根据设备配置和 P4Info 的内容计算。
+//   - p4InfoFormat：从文件读取 P4Info 时使用的格式。
+type fwdPipeConfig struct {
+	action       v1.SetForwardingPipelineConfigRequest_Action
+	cookie       *uint64
+	p4InfoFormat P4InfoFormat
+}
+
+func newFwdPipeConfig(opts []FwdPipeOption) *fwdPipeConfig {
+	config := &fwdPipeConfig{action: v1.SetForwardingPipelineConfigRequest_VERIFY_AND_COMMIT}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return config
+}
+
+// WithPipelineAction 指定安装模式：
+//   - VERIFY：只检查配置，不修改交换机状态。
+//   - VERIFY_AND_SAVE：检查并保存配置，之后通过 CommitFwdPipe 生效，期间转发状态保持不变。
+//   - VERIFY_AND_COMMIT：检查并立即生效，交换机上已有的转发状态会被清空。
+//   - RECONCILE_AND_COMMIT：检查并生效，尽可能保留已有的转发状态。
+//
+// COMMIT 不需要配置，应使用 CommitFwdPipe。
+func WithPipelineAction(action v1.SetForwardingPipelineConfigRequest_Action) FwdPipeOption {
+	return func(config *fwdPipeConfig) {
+		config.action = action
+	}
+}
+
+// WithCookie 指定随配置一起安装的流水线 cookie，替代默认根据内容计算的 cookie
+func WithCookie(cookie uint64) FwdPipeOption {
+	return func(config *fwdPipeConfig) {
+		config.cookie = &cookie
+	}
+}
+
+// WithP4InfoFormat 指定 P4Info 文件的格式，默认为 P4InfoAuto
+func WithP4InfoFormat(format P4InfoFormat) FwdPipeOption {
+	return func(config *fwdPipeConfig) {
+		config.p4InfoFormat = format
+	}
+}
+
+// PipelineCookie 根据设备配置和 P4Info 的内容计算流水线 cookie，相同的程序总是得到相同的 cookie。
+// 不指定 WithCookie 时 SetFwdPipe 使用该 cookie，EnsureFwdPipe 据此判断交换机是否已经运行该程序。
+func PipelineCookie(deviceConfig []byte, p4Info *configv1.P4Info) (uint64, error) {
+	p4InfoBytes, err := proto.MarshalOptions{Deterministic: true}.Marshal(p4Info)
+	if err != nil {
+		return 0, err
+	}
+	h := fnv.New64a()
+	h.Write(deviceConfig)
+	h.Write(p4InfoBytes)
+	return h.Sum64(), nil
+}
+
+// setFwdPipe 发送 SetForwardingPipelineConfig 请求，并在配置生效后更新 P4Info 和 entity
+func (c *Client) setFwdPipe(ctx context.Context, deviceConfig []byte, p4Info *configv1.P4Info, config *fwdPipeConfig) error {
+	if config.action == v1.SetForwardingPipelineConfigRequest_COMMIT {
+		return fmt.Errorf("%s does not take a config, use CommitFwdPipe", config.action)
+	}
+
+	cookie := config.cookie
+	if cookie == nil {
+		contentCookie, err := PipelineCookie(deviceConfig, p4Info)
+		if err != nil {
+			return fmt.Errorf("error when computing pipeline cookie: %v", err)
+		}
+		cookie = &contentCookie
+	}
+
+	req := &v1.SetForwardingPipelineConfigRequest{
+		DeviceId:   c.deviceID,
+		ElectionId: c.ElectionID(),
+		Role:       c.Role().name(),
+		Action:     config.action,
+		Config: &v1.ForwardingPipelineConfig{
+			P4Info:         p4Info,
+			P4DeviceConfig: deviceConfig,
+			Cookie:         &v1.ForwardingPipelineConfig_Cookie{Cookie: *cookie},
+		},
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	if _, err := c.SetForwardingPipelineConfig(ctx, req); err != nil {
+		return err
+	}
+
+	switch config.action {
+	case v1.SetForwardingPipelineConfigRequest_VERIFY_AND_SAVE:
+		c.stateMu.Lock()
+		c.savedP4Info = p4Info
+		c.stateMu.Unlock()
+	case v1.SetForwardingPipelineConfigRequest_VERIFY:
+	default:
+		c.setP4Info(p4Info)
+	}
+	return nil
+}
+
+// CommitFwdPipe 使之前通过 VERIFY_AND_SAVE 保存的配置生效
+func (c *Client) CommitFwdPipe() error {
+	return c.CommitFwdPipeContext(context.Background())
+}
+
+// CommitFwdPipeContext 与 CommitFwdPipe 相同，但使用 ctx 控制截止时间和取消
+func (c *Client) CommitFwdPipeContext(ctx context.Context) error {
+	req := &v1.SetForwardingPipelineConfigRequest{
+		DeviceId:   c.deviceID,
+		ElectionId: c.ElectionID(),
+		Role:       c.Role().name(),
+		Action:     v1.SetForwardingPipelineConfigRequest_COMMIT,
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	if _, err := c.SetForwardingPipelineConfig(ctx, req); err != nil {
+		return err
+	}
+
+	c.stateMu.Lock()
+	saved := c.savedP4Info
+	c.savedP4Info = nil
+	c.stateMu.Unlock()
+	if saved != nil {
+		c.setP4Info(saved)
+	}
+	return nil
+}
+
+// GetFwdPipe 读取交换机上当前安装的流水线，responseType 决定返回的内容：
+//   - ALL：P4Info、设备配置和 cookie。
+//   - COOKIE_ONLY：只返回 cookie。
+//   - P4INFO_AND_COOKIE：P4Info 和 cookie。
+//   - DEVICE_CONFIG_AND_COOKIE：设备配置和 cookie。
+//
+// 返回内容包含 P4Info 时会据此设置客户端的 P4Info 和 entity，使控制器无需原始文件即可接管正在运行的流水线。
+func (c *Client) GetFwdPipe(responseType v1.GetForwardingPipelineConfigRequest_ResponseType) (*v1.ForwardingPipelineConfig, error) {
+	return c.GetFwdPipeContext(context.Background(), responseType)
+}
+
+// GetFwdPipeContext 与 GetFwdPipe 相同，但使用 ctx 控制截止时间和取消
+func (c *Client) GetFwdPipeContext(ctx context.Context, responseType v1.GetForwardingPipelineConfigRequest_ResponseType) (*v1.ForwardingPipelineConfig, error) {
+	req := &v1.GetForwardingPipelineConfigRequest{
+		DeviceId:     c.deviceID,
+		ResponseType: responseType,
+	}
+
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	resp, err := c.GetForwardingPipelineConfig(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	config := resp.GetConfig()
+	switch responseType {
+	case v1.GetForwardingPipelineConfigRequest_ALL, v1.GetForwardingPipelineConfigRequest_P4INFO_AND_COOKIE:
+		if config.GetP4Info() == nil {
+			return nil, errors.New("no forwarding pipeline is installed on the device")
+		}
+		c.setP4Info(config.P4Info)
+	}
+	return config, nil
+}
+
+// InstalledCookie 读取交换机上当前流水线的 cookie，交换机没有安装流水线或流水线没有 cookie 时 ok 为 false
+func (c *Client) InstalledCookie(ctx context.Context) (cookie uint64, ok bool, err error) {
+	config, err := c.GetFwdPipeContext(ctx, v1.GetForwardingPipelineConfigRequest_COOKIE_ONLY)
+	if err != nil {
+		return 0, false, err
+	}
+	if config.GetCookie() == nil {
+		return 0, false, nil
+	}
+	return config.Cookie.Cookie, true, nil
+}
+
+// EnsureFwdPipe 仅在交换机没有运行该程序时安装它，返回是否进行了安装。
+// 交换机上的 cookie 与期望的 cookie（WithCookie 指定的值或根据内容计算的值）相同时跳过安装，
+// 只在本地加载 P4Info，从而在控制器重启时保留交换机上的转发状态。
+func (c *Client) EnsureFwdPipe(binPath string, p4infoPath string, opts ...FwdPipeOption) (bool, error) {
+	return c.EnsureFwdPipeContext(context.Background(), binPath, p4infoPath, opts...)
+}
+
+// EnsureFwdPipeContext 与 EnsureFwdPipe 相同，但使用 ctx 控制截止时间和取消
+func (c *Client) EnsureFwdPipeContext(ctx context.Context, binPath string, p4infoPath string, opts ...FwdPipeOption) (bool, error) {
+	config := newFwdPipeConfig(opts)
+	deviceConfig, p4Info, err := loadPipeline(binPath, p4infoPath, config)
+	if err != nil {
+		return false, err
+	}
+	return c.ensureFwdPipe(ctx, deviceConfig, p4Info, config)
+}
+
+// EnsureFwdPipeConfig 与 EnsureFwdPipeContext 相同，但使用内存中的设备配置和已经解析的 P4Info
+func (c *Client) EnsureFwdPipeConfig(ctx context.Context, deviceConfig []byte, p4Info *configv1.P4Info, opts ...FwdPipeOption) (bool, error) {
+	return c.ensureFwdPipe(ctx, deviceConfig, p4Info, newFwdPipeConfig(opts))
+}
+
+func (c *Client) ensureFwdPipe(ctx context.Context, deviceConfig []byte, p4Info *configv1.P4Info, config *fwdPipeConfig) (bool, error) {
+	if config.cookie == nil {
+		cookie, err := PipelineCookie(deviceConfig, p4Info)
+		if err != nil {
+			return false, fmt.Errorf("error when computing pipeline cookie: %v", err)
+		}
+		config.cookie = &cookie
+	}
+
+	installed, ok, err := c.InstalledCookie(ctx)
+	if err != nil {
+		return false, err
+	}
+	if ok && installed == *config.cookie {
+		c.setP4Info(p4Info)
+		return false, nil
+	}
+
+	if err := c.setFwdPipe(ctx, deviceConfig, p4Info, config); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,298 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// fakePipeline 记录 SetForwardingPipelineConfig 请求，GetForwardingPipelineConfig 返回 installed
+type fakePipeline struct {
+	v1.P4RuntimeClient
+	sets      []*v1.SetForwardingPipelineConfigRequest
+	gets      []*v1.GetForwardingPipelineConfigRequest
+	installed *v1.ForwardingPipelineConfig
+	getErr    error
+}
+
+func (f *fakePipeline) SetForwardingPipelineConfig(_ context.Context, req *v1.SetForwardingPipelineConfigRequest, _ ...grpc.CallOption) (*v1.SetForwardingPipelineConfigResponse, error) {
+	f.sets = append(f.sets, req)
+	return &v1.SetForwardingPipelineConfigResponse{}, nil
+}
+
+func (f *fakePipeline) GetForwardingPipelineConfig(_ context.Context, req *v1.GetForwardingPipelineConfigRequest, _ ...grpc.CallOption) (*v1.GetForwardingPipelineConfigResponse, error) {
+	f.gets = append(f.gets, req)
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+	return &v1.GetForwardingPipelineConfigResponse{Config: f.installed}, nil
+}
+
+func pipelineP4Info(tableName string) *configv1.P4Info {
+	return &configv1.P4Info{Tables: []*configv1.Table{{Preamble: preamble(1, tableName, "")}}}
+}
+
+func mustCookie(t *testing.T, deviceConfig []byte, p4Info *configv1.P4Info) uint64 {
+	t.Helper()
+	cookie, err := PipelineCookie(deviceConfig, p4Info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cookie
+}
+
+func TestPipelineCookie(t *testing.T) {
+	deviceConfig := []byte("bmv2 json")
+	p4Info := pipelineP4Info("ingress.forward")
+	cookie := mustCookie(t, deviceConfig, p4Info)
+
+	tests := []struct {
+		name         string
+		deviceConfig []byte
+		p4Info       *configv1.P4Info
+		wantSame     bool
+	}{
+		{"same program", []byte("bmv2 json"), pipelineP4Info("ingress.forward"), true},
+		{"different device config", []byte("other json"), p4Info, false},
+		{"different P4Info", deviceConfig, pipelineP4Info("ingress.acl"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mustCookie(t, tt.deviceConfig, tt.p4Info); (got == cookie) != tt.wantSame {
+				t.Errorf("PipelineCookie() = %d, cookie of the original program %d, want same %v", got, cookie, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestSetFwdPipeConfigActions(t *testing.T) {
+	deviceConfig := []byte("bmv2 json")
+	p4Info := pipelineP4Info("ingress.forward")
+	contentCookie := mustCookie(t, deviceConfig, p4Info)
+
+	tests := []struct {
+		name       string
+		opts       []FwdPipeOption
+		wantAction v1.SetForwardingPipelineConfigRequest_Action
+		wantCookie uint64
+		wantLoaded bool
+		wantErr    bool
+	}{
+		{"default commits with a content cookie", nil, v1.SetForwardingPipelineConfigRequest_VERIFY_AND_COMMIT, contentCookie, true, false},
+		{"explicit cookie", []FwdPipeOption{WithCookie(42)}, v1.SetForwardingPipelineConfigRequest_VERIFY_AND_COMMIT, 42, true, false},
+		{"verify does not load the P4Info",
+			[]FwdPipeOption{WithPipelineAction(v1.SetForwardingPipelineConfigRequest_VERIFY)},
+			v1.SetForwardingPipelineConfigRequest_VERIFY, contentCookie, false, false},
+		{"verify and save waits for commit",
+			[]FwdPipeOption{WithPipelineAction(v1.SetForwardingPipelineConfigRequest_VERIFY_AND_SAVE)},
+			v1.SetForwardingPipelineConfigRequest_VERIFY_AND_SAVE, contentCookie, false, false},
+		{"reconcile and commit",
+			[]FwdPipeOption{WithPipelineAction(v1.SetForwardingPipelineConfigRequest_RECONCILE_AND_COMMIT)},
+			v1.SetForwardingPipelineConfigRequest_RECONCILE_AND_COMMIT, contentCookie, true, false},
+		{"commit takes no config",
+			[]FwdPipeOption{WithPipelineAction(v1.SetForwardingPipelineConfigRequest_COMMIT)},
+			0, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakePipeline{}
+			c := &Client{P4RuntimeClient: fake, deviceID: 1, electionID: &v1.Uint128{Low: 5}}
+			err := c.SetFwdPipeConfig(context.Background(), deviceConfig, p4Info, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetFwdPipeConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(fake.sets) != 0 {
+					t.Errorf("SetFwdPipeConfig() sent %d requests after an error", len(fake.sets))
+				}
+				return
+			}
+
+			if len(fake.sets) != 1 {
+				t.Fatalf("SetFwdPipeConfig() sent %d requests, want 1", len(fake.sets))
+			}
+			want := &v1.SetForwardingPipelineConfigRequest{
+				DeviceId:   1,
+				ElectionId: &v1.Uint128{Low: 5},
+				Action:     tt.wantAction,
+				Config: &v1.ForwardingPipelineConfig{
+					P4Info:         p4Info,
+					P4DeviceConfig: deviceConfig,
+					Cookie:         &v1.ForwardingPipelineConfig_Cookie{Cookie: tt.wantCookie},
+				},
+			}
+			if !proto.Equal(fake.sets[0], want) {
+				t.Errorf("SetFwdPipeConfig() sent %v, want %v", fake.sets[0], want)
+			}
+			if loaded := c.P4Info() != nil; loaded != tt.wantLoaded {
+				t.Errorf("P4Info loaded = %v, want %v", loaded, tt.wantLoaded)
+			}
+		})
+	}
+}
+
+func TestCommitFwdPipeLoadsSavedP4Info(t *testing.T) {
+	fake := &fakePipeline{}
+	c := &Client{P4RuntimeClient: fake, electionID: &v1.Uint128{Low: 5}}
+	p4Info := pipelineP4Info("ingress.forward")
+	save := WithPipelineAction(v1.SetForwardingPipelineConfigRequest_VERIFY_AND_SAVE)
+	if err := c.SetFwdPipeConfig(context.Background(), nil, p4Info, save); err != nil {
+		t.Fatal(err)
+	}
+	if c.P4Info() != nil {
+		t.Fatal("VERIFY_AND_SAVE loaded the P4Info before commit")
+	}
+
+	if err := c.CommitFwdPipe(); err != nil {
+		t.Fatal(err)
+	}
+	if action := fake.sets[len(fake.sets)-1].GetAction(); action != v1.SetForwardingPipelineConfigRequest_COMMIT {
+		t.Errorf("CommitFwdPipe() sent %v, want COMMIT", action)
+	}
+	if c.P4Info() != p4Info {
+		t.Error("CommitFwdPipe() did not load the saved P4Info")
+	}
+	if _, err := c.Index().Lookup("TABLE", "ingress.forward"); err != nil {
+		t.Errorf("Lookup() after commit error = %v", err)
+	}
+
+	// 没有保存的配置时提交不会修改已有的 P4Info
+	if err := c.CommitFwdPipe(); err != nil {
+		t.Fatal(err)
+	}
+	if c.P4Info() != p4Info {
+		t.Error("second CommitFwdPipe() replaced the P4Info")
+	}
+}
+
+func TestEnsureFwdPipeConfig(t *testing.T) {
+	deviceConfig := []byte("bmv2 json")
+	p4Info := pipelineP4Info("ingress.forward")
+	contentCookie := mustCookie(t, deviceConfig, p4Info)
+	cookieConfig := func(cookie uint64) *v1.ForwardingPipelineConfig {
+		return &v1.ForwardingPipelineConfig{Cookie: &v1.ForwardingPipelineConfig_Cookie{Cookie: cookie}}
+	}
+
+	tests := []struct {
+		name          string
+		installed     *v1.ForwardingPipelineConfig
+		opts          []FwdPipeOption
+		wantInstalled bool
+	}{
+		{"same program is already running", cookieConfig(contentCookie), nil, false},
+		{"different program is running", cookieConfig(contentCookie + 1), nil, true},
+		{"no pipeline installed", nil, nil, true},
+		{"explicit cookie matches", cookieConfig(42), []FwdPipeOption{WithCookie(42)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakePipeline{installed: tt.installed}
+			c := &Client{P4RuntimeClient: fake, electionID: &v1.Uint128{Low: 5}}
+			installed, err := c.EnsureFwdPipeConfig(context.Background(), deviceConfig, p4Info, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if installed != tt.wantInstalled || (len(fake.sets) == 1) != tt.wantInstalled {
+				t.Errorf("EnsureFwdPipeConfig() = %v with %d set requests, want %v", installed, len(fake.sets), tt.wantInstalled)
+			}
+			if len(fake.gets) != 1 || fake.gets[0].GetResponseType() != v1.GetForwardingPipelineConfigRequest_COOKIE_ONLY {
+				t.Errorf("EnsureFwdPipeConfig() sent %v, want one COOKIE_ONLY request", fake.gets)
+			}
+			// 无论是否安装，本地都使用该程序的 P4Info
+			if c.P4Info() != p4Info {
+				t.Error("EnsureFwdPipeConfig() did not load the P4Info")
+			}
+		})
+	}
+}
+
+func TestGetFwdPipe(t *testing.T) {
+	p4Info := pipelineP4Info("ingress.forward")
+	cookie := &v1.ForwardingPipelineConfig_Cookie{Cookie: 42}
+	unavailable := errors.New("switch is down")
+
+	tests := []struct {
+		name         string
+		responseType v1.GetForwardingPipelineConfigRequest_ResponseType
+		installed    *v1.ForwardingPipelineConfig
+		getErr       error
+		wantLoaded   bool
+		wantErr      bool
+	}{
+		{"all", v1.GetForwardingPipelineConfigRequest_ALL,
+			&v1.ForwardingPipelineConfig{P4Info: p4Info, P4DeviceConfig: []byte("bmv2 json"), Cookie: cookie}, nil, true, false},
+		{"P4Info and cookie", v1.GetForwardingPipelineConfigRequest_P4INFO_AND_COOKIE,
+			&v1.ForwardingPipelineConfig{P4Info: p4Info, Cookie: cookie}, nil, true, false},
+		{"cookie only", v1.GetForwardingPipelineConfigRequest_COOKIE_ONLY,
+			&v1.ForwardingPipelineConfig{Cookie: cookie}, nil, false, false},
+		{"device config and cookie", v1.GetForwardingPipelineConfigRequest_DEVICE_CONFIG_AND_COOKIE,
+			&v1.ForwardingPipelineConfig{P4DeviceConfig: []byte("bmv2 json"), Cookie: cookie}, nil, false, false},
+		{"no pipeline installed", v1.GetForwardingPipelineConfigRequest_P4INFO_AND_COOKIE,
+			nil, nil, false, true},
+		{"RPC error", v1.GetForwardingPipelineConfigRequest_ALL,
+			nil, unavailable, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakePipeline{installed: tt.installed, getErr: tt.getErr}
+			c := &Client{P4RuntimeClient: fake, deviceID: 1}
+			got, err := c.GetFwdPipe(tt.responseType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetFwdPipe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.getErr != nil && !errors.Is(err, tt.getErr) {
+				t.Errorf("GetFwdPipe() error = %v, want %v", err, tt.getErr)
+			}
+
+			wantReq := &v1.GetForwardingPipelineConfigRequest{DeviceId: 1, ResponseType: tt.responseType}
+			if len(fake.gets) != 1 || !proto.Equal(fake.gets[0], wantReq) {
+				t.Errorf("GetFwdPipe() sent %v, want %v", fake.gets, wantReq)
+			}
+			if !tt.wantErr && !proto.Equal(got, tt.installed) {
+				t.Errorf("GetFwdPipe() = %v, want %v", got, tt.installed)
+			}
+
+			if loaded := c.P4Info() != nil; loaded != tt.wantLoaded {
+				t.Fatalf("P4Info loaded = %v, want %v", loaded, tt.wantLoaded)
+			}
+			if tt.wantLoaded {
+				if _, err := c.Index().Lookup("TABLE", "ingress.forward"); err != nil {
+					t.Errorf("Lookup() after GetFwdPipe() error = %v", err)
+				}
+				if c.GetEntities("TABLE") == nil {
+					t.Error("GetEntities() after GetFwdPipe() returned nil")
+				}
+			}
+		})
+	}
+}
+
+func TestInstalledCookie(t *testing.T) {
+	tests := []struct {
+		name      string
+		installed *v1.ForwardingPipelineConfig
+		want      uint64
+		wantOK    bool
+	}{
+		{"cookie", &v1.ForwardingPipelineConfig{Cookie: &v1.ForwardingPipelineConfig_Cookie{Cookie: 42}}, 42, true},
+		{"pipeline without cookie", &v1.ForwardingPipelineConfig{}, 0, false},
+		{"no pipeline", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{P4RuntimeClient: &fakePipeline{installed: tt.installed}}
+			cookie, ok, err := c.InstalledCookie(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cookie != tt.want || ok != tt.wantOK {
+				t.Errorf("InstalledCookie() = %d, %v, want %d, %v", cookie, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// Role 描述客户端在仲裁中使用的角色，多个控制器可以分别成为流水线不同部分的主控制器：
+//   - Name：角色名称，为空时使用默认角色，即整个流水线。
+//   - Config：随仲裁请求发送给交换机的角色配置，格式由目标设备定义；为 nil 时角色包含所有 P4 实体。
+//   - Entities：角色可以写入的 P4 实体的完整名称或别名（表、计数器、meter、寄存器、action profile、digest），
+//     Controller 会在本地拒绝写入其他实体；为空时不限制。名称在设置角色或加载 P4Info 时解析，无法解析的名称会报错。
+type Role struct {
+	Name     string
+	Config   *anypb.Any
+	Entities []string
+}
+
+// p4 返回仲裁请求中使用的 v1.Role，默认角色返回 nil
+func (r *Role) p4() *v1.Role {
+	if r == nil || r.Name == "" {
+		return nil
+	}
+	return &v1.Role{
+		Name:   r.Name,
+		Config: r.Config,
+	}
+}
+
+// name 返回读写请求中使用的角色名称，默认角色为空字符串
+func (r *Role) name() string {
+	if r == nil {
+		return ""
+	}
+	return r.Name
+}
+
+// WithRole 设置客户端的角色，见 Role
+func WithRole(role *Role) Option {
+	return func(dc *dialConfig) error {
+		dc.role = role
+		return nil
+	}
+}
+
+// Role 返回客户端当前的角色，默认角色返回 nil
+func (c *Client) Role() *Role {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.role
+}
+
+// SetRole 修改客户端的角色，之后的读写请求和仲裁请求都会使用新的角色。
+// 与 SetElectionID 一样，修改角色不会自动重新仲裁。
+// 已经有 P4Info 时会立即解析 role.Entities，有无法解析的名称时返回错误并保留原来的角色。
+func (c *Client) SetRole(role *Role) error {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	ids, err := resolveRole(role, c.index)
+	if err != nil {
+		return err
+	}
+	c.role = role
+	c.roleEntityIDs = ids
+	c.roleErr = nil
+	return nil
+}
+
+// RoleEntityIDs 返回角色可以写入的 P4 实体 ID，角色不限制实体时返回 nil。
+// 角色中有无法解析的名称时返回解析时的错误，还没有 P4Info 时返回 ErrPipelineNotInstalled。
+func (c *Client) RoleEntityIDs() (map[uint32]bool, error) {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	if c.role == nil || len(c.role.Entities) == 0 {
+		return nil, nil
+	}
+	if c.roleErr != nil {
+		return nil, c.roleErr
+	}
+	if c.roleEntityIDs == nil {
+		return nil, fmt.Errorf("%w: cannot resolve the entities of role %s", ErrPipelineNotInstalled, c.role.Name)
+	}
+	return c.roleEntityIDs, nil
+}
+
+// resolveRoleLocked 在 P4Info 变化后重新解析当前角色的实体，调用者需要持有 stateMu
+func (c *Client) resolveRoleLocked() {
+	c.roleEntityIDs, c.roleErr = resolveRole(c.role, c.index)
+	if c.roleErr != nil {
+		log.Println("Unable to resolve role entities:", c.roleErr)
+	}
+}
+
+// roleEntityTypes 是角色可以包含的实体类型
+var roleEntityTypes = []string{"TABLE", "COUNTER", "METER", "REGISTER", "ACTION_PROFILE", "DIGEST"}
+
+// resolveRole 将 role.Entities 中的名称（完整名称或别名）解析为 P4 ID，同一名称可以对应不同类型的多个实体。
+// 角色不限制实体或还没有 P4Info（index 为 nil）时返回 nil；
+// 名称在所有类型中都找不到时返回 ErrUnknownEntity，别名有歧义时返回 ErrAmbiguousAlias。
+func resolveRole(role *Role, index *P4InfoIndex) (map[uint32]bool, error) {
+	if role == nil || len(role.Entities) == 0 || index == nil {
+		return nil, nil
+	}
+
+	ids := make(map[uint32]bool, len(role.Entities))
+	for _, name := range role.Entities {
+		found := false
+		for _, entityType := range roleEntityTypes {
+			e, err := index.Lookup(entityType, name)
+			switch {
+			case err == nil:
+				ids[e.GetID()] = true
+				found = true
+			case errors.Is(err, ErrAmbiguousAlias):
+				return nil, fmt.Errorf("role %s: %w", role.Name, err)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("role %s: %w: %s", role.Name, ErrUnknownEntity, name)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+)
+
+func TestResolveRole(t *testing.T) {
+	idx := testIndex()
+
+	tests := []struct {
+		name    string
+		role    *Role
+		index   *P4InfoIndex
+		want    map[uint32]bool
+		wantErr error
+	}{
+		{"default role", nil, idx, nil, nil},
+		{"role without entities", &Role{Name: "r"}, idx, nil, nil},
+		{"no P4Info yet", &Role{Name: "r", Entities: []string{"ingress.forward"}}, nil, nil, nil},
+		{"full names", &Role{Name: "r", Entities: []string{"ingress.forward", "egress.forward"}}, idx, map[uint32]bool{1: true, 2: true}, nil},
+		{"unique alias", &Role{Name: "r", Entities: []string{"nat"}}, idx, map[uint32]bool{4: true}, nil},
+		{"unknown name", &Role{Name: "r", Entities: []string{"ingress.forward", "ingress.fwd"}}, idx, nil, ErrUnknownEntity},
+		{"action is not a role entity", &Role{Name: "r", Entities: []string{"ingress.drop_packet"}}, idx, nil, ErrUnknownEntity},
+		{"ambiguous alias", &Role{Name: "r", Entities: []string{"forward"}}, idx, nil, ErrAmbiguousAlias},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveRole(tt.role, tt.index)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("resolveRole() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolveRole() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientRoleEntityIDs(t *testing.T) {
+	c := &Client{role: &Role{Name: "r", Entities: []string{"ingress.nat"}}}
+	if _, err := c.RoleEntityIDs(); !errors.Is(err, ErrPipelineNotInstalled) {
+		t.Fatalf("RoleEntityIDs() before P4Info error = %v, want %v", err, ErrPipelineNotInstalled)
+	}
+
+	c.setP4Info(&configv1.P4Info{Tables: []*configv1.Table{
+		{Preamble: preamble(4, "ingress.nat", "nat")},
+		{Preamble: preamble(5, "ingress.acl", "acl")},
+	}})
+	ids, err := c.RoleEntityIDs()
+	if err != nil || !reflect.DeepEqual(ids, map[uint32]bool{4: true}) {
+		t.Fatalf("RoleEntityIDs() after P4Info = %v, %v, want map[4:true]", ids, err)
+	}
+
+	if err := c.SetRole(&Role{Name: "r2", Entities: []string{"ingress.typo"}}); !errors.Is(err, ErrUnknownEntity) {
+		t.Fatalf("SetRole() with an unknown entity error = %v, want %v", err, ErrUnknownEntity)
+	}
+	if c.Role().Name != "r" {
+		t.Errorf("SetRole() with an unknown entity replaced the role with %s", c.Role().Name)
+	}
+
+	if err := c.SetRole(&Role{Name: "r3", Entities: []string{"acl"}}); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := c.RoleEntityIDs(); err != nil || !reflect.DeepEqual(ids, map[uint32]bool{5: true}) {
+		t.Errorf("RoleEntityIDs() after SetRole = %v, %v, want map[5:true]", ids, err)
+	}
+
+	// 新的 P4Info 中没有角色的实体
+	c.setP4Info(&configv1.P4Info{Tables: []*configv1.Table{{Preamble: preamble(4, "ingress.nat", "nat")}}})
+	if _, err := c.RoleEntityIDs(); !errors.Is(err, ErrUnknownEntity) {
+		t.Errorf("RoleEntityIDs() after losing the entity error = %v, want %v", err, ErrUnknownEntity)
+	}
+
+	if err := c.SetRole(nil); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := c.RoleEntityIDs(); ids != nil || err != nil {
+		t.Errorf("RoleEntityIDs() for the default role = %v, %v, want nil, nil", ids, err)
+	}
+}
@@ -0,0 +1,266 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// ConnectionState 表示客户端与交换机之间 StreamChannel 的状态
+type ConnectionState int
+
+const (
+	// Disconnected 流已断开，尚未开始重连
+	Disconnected ConnectionState = iota
+	// Connected 流已建立，可以收发消息
+	Connected
+	// Reconnecting 正在按退避策略重新建立流
+	Reconnecting
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Disconnected:
+		return "DISCONNECTED"
+	case Connected:
+		return "CONNECTED"
+	case Reconnecting:
+		return "RECONNECTING"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// ReconnectPolicy 描述流断开后的指数退避重连策略：
+//   - InitialBackoff：第一次重连前的等待时间。
+//   - MaxBackoff：等待时间的上限。
+//   - Multiplier：每次重连失败后等待时间的倍数。
+type ReconnectPolicy struct {
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+}
+
+// DefaultReconnectPolicy 是客户端默认使用的重连策略
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialBackoff: 500 * time.Millisecond,
+	MaxBackoff:     30 * time.Second,
+	Multiplier:     2,
+}
+
+// next 返回下一次重连前的等待时间
+func (p ReconnectPolicy) next(backoff time.Duration) time.Duration {
+	backoff = time.Duration(float64(backoff) * p.Multiplier)
+	if backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+// normalize 修正不合理的取值，保证等待时间大于 0 且逐次增长，避免重连循环空转：
+// InitialBackoff 不大于 0 或 Multiplier 不大于 1 时使用 DefaultReconnectPolicy 中的值，
+// MaxBackoff 小于 InitialBackoff 时取 InitialBackoff。
+func (p ReconnectPolicy) normalize() ReconnectPolicy {
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = DefaultReconnectPolicy.InitialBackoff
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		p.MaxBackoff = p.InitialBackoff
+	}
+	if p.Multiplier <= 1 {
+		p.Multiplier = DefaultReconnectPolicy.Multiplier
+	}
+	return p
+}
+
+// SetReconnectPolicy 设置流断开后的重连策略，应在 Run 之前调用。不合理的取值会按 ReconnectPolicy.normalize 修正。
+func (c *Client) SetReconnectPolicy(policy ReconnectPolicy) {
+	c.reconnectPolicy = policy.normalize()
+}
+
+// ConnectionState 返回当前的连接状态
+func (c *Client) ConnectionState() ConnectionState {
+	c.stateMu.RLock()
+	defer c.stateMu.RUnlock()
+	return c.state
+}
+
+// ConnectionStateChannel 返回连接状态变化的通道。
+// 通道带缓冲，缓冲区满时新的状态会被丢弃，因此使用者应及时读取。
+func (c *Client) ConnectionStateChannel() <-chan ConnectionState {
+	return c.stateChannel
+}
+
+func (c *Client) setState(state ConnectionState) {
+	c.stateMu.Lock()
+	changed := c.state != state
+	c.state = state
+	c.stateMu.Unlock()
+
+	if !changed {
+		return
+	}
+	select {
+	case c.stateChannel <- state:
+	default:
+		log.Println("Connection state channel is full, dropping state", state)
+	}
+}
+
+// OutgoingBufferSize 是 OutgoingMessageChannel 的容量，流断开期间最多缓存这么多条待发送的消息
+const OutgoingBufferSize = 100
+
+var (
+	// ErrOutgoingBufferFull 表示待发送的消息已经填满 OutgoingMessageChannel，通常是因为流长时间断开
+	ErrOutgoingBufferFull = errors.New("outgoing message buffer is full")
+	// ErrClientClosed 表示客户端已经通过 Close 关闭
+	ErrClientClosed = errors.New("client is closed")
+)
+
+// SendMessage 将 message 放入 OutgoingMessageChannel，由 StreamChannel 发送。
+// 流断开期间消息缓存在通道中，重连后按顺序发送；缓冲区已满或客户端已关闭时立即返回错误，不会阻塞。
+func (c *Client) SendMessage(message *v1.StreamMessageRequest) error {
+	if c.ctx.Err() != nil {
+		return ErrClientClosed
+	}
+	select {
+	case c.OutgoingMessageChannel <- message:
+		return nil
+	default:
+		return fmt.Errorf("%w, connection state is %v", ErrOutgoingBufferFull, c.ConnectionState())
+	}
+}
+
+// Close 停止维护 StreamChannel 并关闭 gRPC 连接，之后客户端不能再使用。重复调用是安全的。
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.cancel()
+		if c.conn != nil {
+			err = c.conn.Close()
+		}
+	})
+	return err
+}
+
+// superviseStream 建立 StreamChannel 并在其断开后按 ReconnectPolicy 重连，直到客户端被关闭。
+// 重连成功后会重新发送 MasterArbitrationUpdate，因为交换机在流断开时会撤销该客户端的主控权。
+func (c *Client) superviseStream() {
+	defer c.setState(Disconnected)
+
+	backoff := c.reconnectPolicy.InitialBackoff
+	reconnect := false
+	for c.ctx.Err() == nil {
+		ctx, cancel := context.WithCancel(c.ctx)
+		stream, err := c.StreamChannel(ctx)
+		if err == nil && reconnect {
+			err = stream.Send(c.arbitrationRequest())
+		}
+		if err != nil {
+			cancel()
+			if c.ctx.Err() != nil {
+				return
+			}
+			log.Println("Unable to establish StreamChannel, retrying in", backoff, err)
+			c.setState(Reconnecting)
+			select {
+			case <-time.After(backoff):
+			case <-c.ctx.Done():
+				return
+			}
+			backoff = c.reconnectPolicy.next(backoff)
+			continue
+		}
+
+		backoff = c.reconnectPolicy.InitialBackoff
+		c.stateMu.Lock()
+		c.streamChannel = stream
+		c.stateMu.Unlock()
+		c.setState(Connected)
+
+		c.runStream(stream, cancel)
+
+		c.SetMastershipStatus(false)
+		c.setState(Disconnected)
+		reconnect = true
+	}
+}
+
+// runStream 在流上收发消息，直到流断开后调用 cancel 并返回：
+// 接收到的消息发送到 IncomingMessageChannel，OutgoingMessageChannel 中的消息发送到流上。
+// 发送失败的消息保存在 unsent 中，在下一个流上最先发送，因此流断开不会丢失已经取出的消息。
+func (c *Client) runStream(stream v1.P4Runtime_StreamChannelClient, cancel context.CancelFunc) {
+	done := make(chan struct{})
+	senderDone := make(chan struct{})
+	defer func() {
+		cancel()
+		close(done)
+		<-senderDone
+	}()
+
+	// 发送消息的 goroutine，流断开或发送失败后退出
+	go func() {
+		defer close(senderDone)
+		message := c.takeUnsent()
+		for {
+			if message == nil {
+				select {
+				case <-done:
+					return
+				case message = <-c.OutgoingMessageChannel:
+				}
+			}
+			if err := stream.Send(message); err != nil {
+				log.Println("Unable to send message to stream, it will be resent after reconnecting:", err)
+				c.stateMu.Lock()
+				c.unsent = message
+				c.stateMu.Unlock()
+				return
+			}
+			message = nil
+		}
+	}()
+
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			log.Println("Stream closed by server")
+			return
+		}
+		if err != nil {
+			log.Println("Error receiving message from stream:", err)
+			return
+		}
+
+		select {
+		case c.IncomingMessageChannel <- in:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// takeUnsent 取出上一个流上发送失败的消息
+func (c *Client) takeUnsent() *v1.StreamMessageRequest {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	message := c.unsent
+	c.unsent = nil
+	return message
+}
+
+// arbitrationRequest 根据客户端的设备 ID 和选举 ID 构造仲裁请求
+func (c *Client) arbitrationRequest() *v1.StreamMessageRequest {
+	return &v1.StreamMessageRequest{
+		Update: &v1.StreamMessageRequest_Arbitration{Arbitration: &v1.MasterArbitrationUpdate{
+			DeviceId:   c.deviceID,
+			ElectionId: c.ElectionID(),
+			Role:       c.Role().p4(),
+		}},
+	}
+}
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc"
+)
+
+// fakeStream 是一个 StreamChannel，sendErr 不为 nil 时第一次 Send 失败并使流断开，
+// 成功发送的消息写入 sent，Recv 一直阻塞到流断开或 ctx 被取消
+type fakeStream struct {
+	v1.P4Runtime_StreamChannelClient
+	ctx     context.Context
+	sendErr error
+	sent    chan *v1.StreamMessageRequest
+	broken  chan struct{}
+}
+
+func (s *fakeStream) Send(message *v1.StreamMessageRequest) error {
+	if s.sendErr != nil {
+		close(s.broken)
+		return s.sendErr
+	}
+	s.sent <- message
+	return nil
+}
+
+func (s *fakeStream) Recv() (*v1.StreamMessageResponse, error) {
+	select {
+	case <-s.broken:
+		return nil, s.sendErr
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// fakeStreamClient 依次返回 streams 中的流，用完后返回错误
+type fakeStreamClient struct {
+	v1.P4RuntimeClient
+	mu      sync.Mutex
+	streams []*fakeStream
+}
+
+func (f *fakeStreamClient) StreamChannel(ctx context.Context, _ ...grpc.CallOption) (v1.P4Runtime_StreamChannelClient, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.streams) == 0 {
+		return nil, errors.New("switch is down")
+	}
+	stream := f.streams[0]
+	f.streams = f.streams[1:]
+	stream.ctx = ctx
+	stream.broken = make(chan struct{})
+	return stream, nil
+}
+
+// streamTestClient 返回一个不需要 gRPC 连接的客户端，用于测试 StreamChannel 的维护
+func streamTestClient(p4RtC v1.P4RuntimeClient, bufferSize int) *Client {
+	c := &Client{
+		P4RuntimeClient:        p4RtC,
+		electionID:             &v1.Uint128{Low: 1},
+		IncomingMessageChannel: make(chan *v1.StreamMessageResponse),
+		OutgoingMessageChannel: make(chan *v1.StreamMessageRequest, bufferSize),
+		stateChannel:           make(chan ConnectionState, 10),
+		reconnectPolicy:        ReconnectPolicy{InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 2},
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	return c
+}
+
+func packetOut(payload string) *v1.StreamMessageRequest {
+	return &v1.StreamMessageRequest{Update: &v1.StreamMessageRequest_Packet{Packet: &v1.PacketOut{Payload: []byte(payload)}}}
+}
+
+// superviseInBackground 在后台运行 superviseStream，返回的通道在其退出后关闭
+func superviseInBackground(c *Client) <-chan struct{} {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		c.superviseStream()
+	}()
+	return stopped
+}
+
+func waitStopped(t *testing.T, stopped <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("superviseStream did not return after Close")
+	}
+}
+
+func TestReconnectPolicyNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ReconnectPolicy
+		want   ReconnectPolicy
+	}{
+		{"default is unchanged", DefaultReconnectPolicy, DefaultReconnectPolicy},
+		{"zero policy uses defaults", ReconnectPolicy{}, ReconnectPolicy{
+			InitialBackoff: DefaultReconnectPolicy.InitialBackoff,
+			MaxBackoff:     DefaultReconnectPolicy.InitialBackoff,
+			Multiplier:     DefaultReconnectPolicy.Multiplier,
+		}},
+		{"multiplier of one", ReconnectPolicy{time.Second, time.Minute, 1}, ReconnectPolicy{time.Second, time.Minute, DefaultReconnectPolicy.Multiplier}},
+		{"negative initial backoff", ReconnectPolicy{-time.Second, time.Minute, 3}, ReconnectPolicy{DefaultReconnectPolicy.InitialBackoff, time.Minute, 3}},
+		{"max below initial", ReconnectPolicy{time.Second, time.Millisecond, 1.5}, ReconnectPolicy{time.Second, time.Second, 1.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.normalize()
+			if got != tt.want {
+				t.Fatalf("normalize() = %+v, want %+v", got, tt.want)
+			}
+			backoff := got.InitialBackoff
+			for i := 0; i < 100; i++ {
+				next := got.next(backoff)
+				if next <= 0 || (next <= backoff && next != got.MaxBackoff) {
+					t.Fatalf("next(%v) = %v, backoff does not grow towards %v", backoff, next, got.MaxBackoff)
+				}
+				backoff = next
+			}
+			if backoff != got.MaxBackoff {
+				t.Errorf("backoff settled at %v, want %v", backoff, got.MaxBackoff)
+			}
+		})
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		queued  int
+		closed  bool
+		wantErr error
+	}{
+		{"queued while disconnected", 1, false, nil},
+		{"buffer full", 2, false, ErrOutgoingBufferFull},
+		{"client closed", 0, true, ErrClientClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := streamTestClient(&fakeStreamClient{}, 2)
+			for i := 0; i < tt.queued; i++ {
+				if err := c.SendMessage(packetOut("queued")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.closed {
+				c.Close()
+			}
+
+			done := make(chan error)
+			go func() { done <- c.SendMessage(packetOut("new")) }()
+			select {
+			case err := <-done:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("SendMessage() error = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("SendMessage() blocked")
+			}
+		})
+	}
+}
+
+func TestSuperviseStreamResendsUnsentMessage(t *testing.T) {
+	second := &fakeStream{sent: make(chan *v1.StreamMessageRequest, 10)}
+	c := streamTestClient(&fakeStreamClient{streams: []*fakeStream{
+		{sendErr: errors.New("stream reset")},
+		second,
+	}}, 10)
+	if err := c.SendMessage(packetOut("first")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SendMessage(packetOut("second")); err != nil {
+		t.Fatal(err)
+	}
+	stopped := superviseInBackground(c)
+
+	// 重连后先发送仲裁请求，然后按顺序发送两条报文，包括在第一个流上发送失败的那条
+	var got []*v1.StreamMessageRequest
+	for len(got) < 3 {
+		select {
+		case message := <-second.sent:
+			got = append(got, message)
+		case <-time.After(time.Second):
+			t.Fatalf("received %d messages on the new stream, want 3", len(got))
+		}
+	}
+	if got[0].GetArbitration() == nil {
+		t.Errorf("first message on the new stream = %v, want the arbitration request", got[0])
+	}
+	for i, want := range []string{"first", "second"} {
+		if payload := string(got[i+1].GetPacket().GetPayload()); payload != want {
+			t.Errorf("message %d has payload %q, want %q", i+1, payload, want)
+		}
+	}
+
+	c.Close()
+	waitStopped(t, stopped)
+}
+
+func TestCloseStopsReconnecting(t *testing.T) {
+	c := streamTestClient(&fakeStreamClient{}, 1)
+	c.reconnectPolicy = ReconnectPolicy{InitialBackoff: time.Hour, MaxBackoff: time.Hour, Multiplier: 2}
+	stopped := superviseInBackground(c)
+
+	// 等待进入重连的退避等待，Close 应该立即结束等待
+	for c.ConnectionState() != Reconnecting {
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitStopped(t, stopped)
+	if state := c.ConnectionState(); state != Disconnected {
+		t.Errorf("ConnectionState() = %v after Close, want %v", state, Disconnected)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("second Close() error = %v", err)
+	}
+}
@@ -0,0 +1,260 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
+	"p4r/entity"
+)
+
+// ActionProfileControl 用于操作 P4 中的 action profile 和 action selector。
+// 主要功能包括 member 的增删改查，以及（仅 action selector）带权重和监视端口的 group 的增删改查。
+type ActionProfileControl struct {
+	batchWriter
+	actionProfile *entity.ActionProfile
+}
+
+// WithBatch 返回一个写操作累积到 batch 中的 ActionProfileControl，调用 batch.Send 时才会真正发送
+func (apc ActionProfileControl) WithBatch(batch *client.Batch) ActionProfileControl {
+	apc.batch = batch
+	return apc
+}
+
+// action 根据完整名称或别名查找动作实体
+func (sc *Controller) action(name string) (*entity.Action, error) {
+	e, err := sc.Client.Index().Lookup("ACTION", name)
+	if err != nil {
+		return nil, err
+	}
+	return e.(*entity.Action), nil
+}
+
+// actionByID 根据 ID 查找动作实体，用于解码交换机返回的表项
+func (sc *Controller) actionByID(id uint32) (*entity.Action, error) {
+	e, err := sc.Client.Index().LookupID(id)
+	if err != nil {
+		return nil, err
+	}
+	a, ok := e.(*entity.Action)
+	if !ok {
+		return nil, fmt.Errorf("ID %d is a %s, not an action", id, e.Type())
+	}
+	return a, nil
+}
+
+// decodeAction 将 v1.Action 解码为动作名称和以名称为键的参数
+func (sc *Controller) decodeAction(action *v1.Action) (string, map[string][]byte, error) {
+	a, err := sc.actionByID(action.ActionId)
+	if err != nil {
+		return "", nil, err
+	}
+	params, err := a.DecodeParams(action.Params)
+	if err != nil {
+		return "", nil, err
+	}
+	return a.Name, params, nil
+}
+
+// actionSet 将以名称表示的 WeightedAction 转换为 one-shot 表项使用的 TableAction
+func (sc *Controller) actionSet(actions []WeightedAction) (*v1.TableAction, error) {
+	profileActions := make([]*v1.ActionProfileAction, 0, len(actions))
+	for _, wa := range actions {
+		a, err := sc.action(wa.Action)
+		if err != nil {
+			return nil, err
+		}
+		profileAction, err := entity.NewActionProfileAction(a, wa.Params, wa.Weight, wa.WatchPort)
+		if err != nil {
+			return nil, err
+		}
+		profileActions = append(profileActions, profileAction)
+	}
+	return entity.ActionSetTableAction(profileActions), nil
+}
+
+// InsertMember 插入一个绑定 action 及其参数的 member
+func (apc ActionProfileControl) InsertMember(memberID uint32, action string, params map[string][]byte) error {
+	return apc.InsertMemberContext(context.Background(), memberID, action, params)
+}
+
+// InsertMemberContext 与 InsertMember 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) InsertMemberContext(ctx context.Context, memberID uint32, action string, params map[string][]byte) error {
+	a, err := apc.control.action(action)
+	if err != nil {
+		return err
+	}
+	update, err := apc.actionProfile.InsertMember(memberID, a, params)
+	if err != nil {
+		return err
+	}
+	return apc.writeUpdate(ctx, update)
+}
+
+// ModifyMember 修改 member 的 action 及其参数
+func (apc ActionProfileControl) ModifyMember(memberID uint32, action string, params map[string][]byte) error {
+	return apc.ModifyMemberContext(context.Background(), memberID, action, params)
+}
+
+// ModifyMemberContext 与 ModifyMember 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) ModifyMemberContext(ctx context.Context, memberID uint32, action string, params map[string][]byte) error {
+	a, err := apc.control.action(action)
+	if err != nil {
+		return err
+	}
+	update, err := apc.actionProfile.ModifyMember(memberID, a, params)
+	if err != nil {
+		return err
+	}
+	return apc.writeUpdate(ctx, update)
+}
+
+// DeleteMember 删除一个 member
+func (apc ActionProfileControl) DeleteMember(memberID uint32) error {
+	return apc.DeleteMemberContext(context.Background(), memberID)
+}
+
+// DeleteMemberContext 与 DeleteMember 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) DeleteMemberContext(ctx context.Context, memberID uint32) error {
+	return apc.writeUpdate(ctx, apc.actionProfile.DeleteMember(memberID))
+}
+
+// ReadMember 读取一个 member
+func (apc ActionProfileControl) ReadMember(memberID uint32) (*ActionProfileMemberData, error) {
+	return apc.ReadMemberContext(context.Background(), memberID)
+}
+
+// ReadMemberContext 与 ReadMember 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) ReadMemberContext(ctx context.Context, memberID uint32) (*ActionProfileMemberData, error) {
+	members, err := apc.readMembers(ctx, apc.actionProfile.ReadMember(memberID))
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return nil, errors.New("No action profile member found")
+	}
+	return members[0], nil
+}
+
+// ReadAllMembers 读取 action profile 中的所有 member
+func (apc ActionProfileControl) ReadAllMembers() ([]*ActionProfileMemberData, error) {
+	return apc.ReadAllMembersContext(context.Background())
+}
+
+// ReadAllMembersContext 与 ReadAllMembers 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) ReadAllMembersContext(ctx context.Context) ([]*ActionProfileMemberData, error) {
+	return apc.readMembers(ctx, apc.actionProfile.ReadMember(0))
+}
+
+func (apc ActionProfileControl) readMembers(ctx context.Context, e *v1.Entity) ([]*ActionProfileMemberData, error) {
+	res, err := apc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{e})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ActionProfileMemberData, 0, len(res))
+	for _, item := range res {
+		member := item.GetActionProfileMember()
+		if member == nil {
+			return nil, errors.New("Entity is not an action profile member")
+		}
+		action, params, err := apc.control.decodeAction(member.Action)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &ActionProfileMemberData{
+			MemberID: member.MemberId,
+			Action:   action,
+			Params:   params,
+		})
+	}
+	return result, nil
+}
+
+// InsertGroup 插入一个 group，maxSize 为 group 将来可以容纳的 member 数量上限，0 表示不指定
+func (apc ActionProfileControl) InsertGroup(groupID uint32, members []entity.GroupMember, maxSize int32) error {
+	return apc.InsertGroupContext(context.Background(), groupID, members, maxSize)
+}
+
+// InsertGroupContext 与 InsertGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) InsertGroupContext(ctx context.Context, groupID uint32, members []entity.GroupMember, maxSize int32) error {
+	update, err := apc.actionProfile.InsertGroup(groupID, members, maxSize)
+	if err != nil {
+		return err
+	}
+	return apc.writeUpdate(ctx, update)
+}
+
+// ModifyGroup 替换 group 的 member 列表
+func (apc ActionProfileControl) ModifyGroup(groupID uint32, members []entity.GroupMember, maxSize int32) error {
+	return apc.ModifyGroupContext(context.Background(), groupID, members, maxSize)
+}
+
+// ModifyGroupContext 与 ModifyGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) ModifyGroupContext(ctx context.Context, groupID uint32, members []entity.GroupMember, maxSize int32) error {
+	update, err := apc.actionProfile.ModifyGroup(groupID, members, maxSize)
+	if err != nil {
+		return err
+	}
+	return apc.writeUpdate(ctx, update)
+}
+
+// DeleteGroup 删除一个 group
+func (apc ActionProfileControl) DeleteGroup(groupID uint32) error {
+	return apc.DeleteGroupContext(context.Background(), groupID)
+}
+
+// DeleteGroupContext 与 DeleteGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) DeleteGroupContext(ctx context.Context, groupID uint32) error {
+	return apc.writeUpdate(ctx, apc.actionProfile.DeleteGroup(groupID))
+}
+
+// ReadGroup 读取一个 group
+func (apc ActionProfileControl) ReadGroup(groupID uint32) (*ActionProfileGroupData, error) {
+	return apc.ReadGroupContext(context.Background(), groupID)
+}
+
+// ReadGroupContext 与 ReadGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) ReadGroupContext(ctx context.Context, groupID uint32) (*ActionProfileGroupData, error) {
+	groups, err := apc.readGroups(ctx, apc.actionProfile.ReadGroup(groupID))
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, errors.New("No action profile group found")
+	}
+	return groups[0], nil
+}
+
+// ReadAllGroups 读取 action selector 中的所有 group
+func (apc ActionProfileControl) ReadAllGroups() ([]*ActionProfileGroupData, error) {
+	return apc.ReadAllGroupsContext(context.Background())
+}
+
+// ReadAllGroupsContext 与 ReadAllGroups 相同，但使用 ctx 控制请求的截止时间和取消
+func (apc ActionProfileControl) ReadAllGroupsContext(ctx context.Context) ([]*ActionProfileGroupData, error) {
+	return apc.readGroups(ctx, apc.actionProfile.ReadGroup(0))
+}
+
+func (apc ActionProfileControl) readGroups(ctx context.Context, e *v1.Entity) ([]*ActionProfileGroupData, error) {
+	res, err := apc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{e})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ActionProfileGroupData, 0, len(res))
+	for _, item := range res {
+		group := item.GetActionProfileGroup()
+		if group == nil {
+			return nil, errors.New("Entity is not an action profile group")
+		}
+		result = append(result, &ActionProfileGroupData{
+			GroupID: group.GroupId,
+			Members: entity.DecodeGroupMembers(group.Members),
+			MaxSize: group.MaxSize,
+		})
+	}
+	return result, nil
+}
@@ -1,38 +1,130 @@
 package control
 
 import (
+	"context"
+	"errors"
 	"log"
 
 	"github.com/p4lang/p4runtime/go/p4/v1"
 	"google.golang.org/genproto/googleapis/rpc/code"
+	"p4r/client"
 )
 
+// ErrNotPrimary 表示控制器当前不是主控制器，交换机会拒绝它的写请求
+var ErrNotPrimary = errors.New("control is not the primary controller")
+
 // PerformArbitration 通过向交换机发送 仲裁请求 来参与仲裁流程。
-func (sc *Controller) PerformArbitration() {
-	msgChannels := sc.Client.GetMessageChannels()
-	outChan := msgChannels.OutgoingMessageChannel
+// 流断开时请求会在重连后发送；发送缓冲区已满或客户端已关闭时返回错误，不会阻塞。
+func (sc *Controller) PerformArbitration() error {
 	arbitrationData := sc.Client.GetArbitrationData()
 
 	request := &v1.StreamMessageRequest{
 		Update: &v1.StreamMessageRequest_Arbitration{Arbitration: &v1.MasterArbitrationUpdate{
 			DeviceId:   arbitrationData.DeviceID,
-			ElectionId: &(arbitrationData.ElectionID),
+			ElectionId: arbitrationData.ElectionID,
+			Role:       arbitrationData.Role,
 		}},
 	}
 
-	outChan <- request
+	return sc.Client.SendMessage(request)
 }
 
-// StartArbitrationUpdateListener 启动了一个 监听仲裁更新 的 goroutine，目的是检查仲裁结果，决定控制器是否获得主控权
+// StartArbitrationUpdateListener 启动了一个 监听仲裁更新 的 goroutine，在流的整个生命周期内检查仲裁结果。
+// 交换机会在其他控制器加入、离开或修改选举 ID 时重新发送仲裁结果，控制器据此获得或失去主控权。
 func (sc *Controller) StartArbitrationUpdateListener() {
 	go func() {
-		update := <-sc.ArbitrationChannel
-		if update.Arbitration.Status.Code != int32(code.Code_OK) {
-			sc.SetMastershipStatus(false)
-			log.Println("Arbitration was done. Control did not acquire mastership.")
-		} else {
-			sc.SetMastershipStatus(true)
-			log.Println("Arbitration was done. Control acquired mastership")
+		for update := range sc.ArbitrationChannel {
+			sc.handleArbitrationUpdate(update.Arbitration)
 		}
 	}()
 }
+
+// handleArbitrationUpdate 根据交换机返回的仲裁结果更新主控权状态
+func (sc *Controller) handleArbitrationUpdate(arbitration *v1.MasterArbitrationUpdate) {
+	isMaster := arbitration.GetStatus().GetCode() == int32(code.Code_OK)
+	if isMaster {
+		log.Println("Arbitration was done. Control acquired mastership")
+	} else {
+		log.Println("Arbitration was done. Control did not acquire mastership.")
+	}
+
+	sc.Client.SetMastershipStatus(isMaster)
+	sc.masterMu.Lock()
+	sc.primaryElectionID = arbitration.GetElectionId()
+	sc.masterMu.Unlock()
+	sc.notifyMastership(&MastershipData{
+		IsMaster:          isMaster,
+		PrimaryElectionID: arbitration.GetElectionId(),
+		Message:           arbitration.GetStatus().GetMessage(),
+	})
+}
+
+// notifyMastership 在主控权发生变化时发送到 MastershipChannel，并在第一次仲裁完成时通知 Run 返回。
+// MastershipChannel 满时丢弃变化，避免阻塞仲裁监听。
+func (sc *Controller) notifyMastership(data *MastershipData) {
+	sc.masterMu.Lock()
+	changed := !sc.arbitrated || sc.lastMaster != data.IsMaster
+	sc.arbitrated = true
+	sc.lastMaster = data.IsMaster
+	sc.masterMu.Unlock()
+
+	select {
+	case sc.setupNotifChannel <- data.IsMaster:
+	default:
+	}
+
+	if !changed {
+		return
+	}
+	select {
+	case sc.MastershipChannel <- data:
+	default:
+		log.Println("Mastership channel is full, dropping mastership change")
+	}
+}
+
+// StartConnectionMonitor 启动一个监听连接状态的 goroutine。
+// 流断开时客户端会失去主控权；重连后客户端会自动重新发送仲裁请求，其结果由仲裁更新监听处理。
+func (sc *Controller) StartConnectionMonitor() {
+	stateChannel := sc.Client.ConnectionStateChannel()
+	go func() {
+		for state := range stateChannel {
+			log.Println("Connection state changed to", state)
+			if state == client.Disconnected {
+				sc.SetMastershipStatus(false)
+			}
+		}
+	}()
+}
+
+// guardedClient 包装 P4RClient，在控制器不是主控制器或写入角色之外的实体时直接拒绝写请求，而不是发送给交换机
+type guardedClient struct {
+	client.P4RClient
+	control *Controller
+}
+
+func (pc guardedClient) WriteUpdate(update *v1.Update) error {
+	return pc.WriteUpdateContext(context.Background(), update)
+}
+
+func (pc guardedClient) WriteUpdateContext(ctx context.Context, update *v1.Update) error {
+	return pc.WriteUpdatesContext(ctx, []*v1.Update{update}, v1.WriteRequest_CONTINUE_ON_ERROR)
+}
+
+func (pc guardedClient) WriteUpdates(updates []*v1.Update, atomicity v1.WriteRequest_Atomicity) error {
+	return pc.WriteUpdatesContext(context.Background(), updates, atomicity)
+}
+
+func (pc guardedClient) WriteUpdatesContext(ctx context.Context, updates []*v1.Update, atomicity v1.WriteRequest_Atomicity) error {
+	if !pc.control.IsMaster() {
+		return ErrNotPrimary
+	}
+	if err := pc.control.checkRole(updates); err != nil {
+		return err
+	}
+	return pc.P4RClient.WriteUpdatesContext(ctx, updates, atomicity)
+}
+
+func (pc guardedClient) NewBatch(atomicity v1.WriteRequest_Atomicity) *client.Batch {
+	return client.NewBatch(pc, atomicity)
+}
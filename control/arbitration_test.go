@@ -0,0 +1,169 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
+	"p4r/client"
+)
+
+// fakeMasterClient 记录主控权状态和写请求
+type fakeMasterClient struct {
+	client.P4RClient
+	mu       sync.Mutex
+	isMaster bool
+	writes   int
+	states   chan client.ConnectionState
+}
+
+func (f *fakeMasterClient) IsMaster() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.isMaster
+}
+
+func (f *fakeMasterClient) SetMastershipStatus(status bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.isMaster = status
+}
+
+func (f *fakeMasterClient) RoleEntityIDs() (map[uint32]bool, error) {
+	return nil, nil
+}
+
+func (f *fakeMasterClient) WriteUpdatesContext(context.Context, []*v1.Update, v1.WriteRequest_Atomicity) error {
+	f.writes++
+	return nil
+}
+
+func (f *fakeMasterClient) ConnectionStateChannel() <-chan client.ConnectionState {
+	return f.states
+}
+
+func newMastershipController(fake *fakeMasterClient) *Controller {
+	sc := &Controller{
+		MastershipChannel: make(chan *MastershipData, 10),
+		setupNotifChannel: make(chan bool, 1),
+	}
+	sc.Client = guardedClient{P4RClient: fake, control: sc}
+	return sc
+}
+
+func arbitrationUpdate(c code.Code, electionID uint64) *v1.MasterArbitrationUpdate {
+	return &v1.MasterArbitrationUpdate{
+		DeviceId:   1,
+		ElectionId: &v1.Uint128{Low: electionID},
+		Status:     &status.Status{Code: int32(c), Message: c.String()},
+	}
+}
+
+func TestHandleArbitrationUpdate(t *testing.T) {
+	// 每一步之后的主控权状态，以及是否应该通知 MastershipChannel
+	steps := []struct {
+		name        string
+		update      *v1.MasterArbitrationUpdate
+		wantMaster  bool
+		wantPrimary uint64
+		wantNotify  bool
+	}{
+		{"first result as backup is notified", arbitrationUpdate(code.Code_ALREADY_EXISTS, 20), false, 20, true},
+		{"promoted when the primary leaves", arbitrationUpdate(code.Code_OK, 10), true, 10, true},
+		{"repeated result is not notified", arbitrationUpdate(code.Code_OK, 10), true, 10, false},
+		{"demoted when a higher election ID joins", arbitrationUpdate(code.Code_ALREADY_EXISTS, 30), false, 30, true},
+		{"primary change while backup is not notified", arbitrationUpdate(code.Code_NOT_FOUND, 40), false, 40, false},
+	}
+
+	fake := &fakeMasterClient{}
+	sc := newMastershipController(fake)
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			sc.handleArbitrationUpdate(step.update)
+			if sc.IsMaster() != step.wantMaster {
+				t.Errorf("IsMaster() = %v, want %v", sc.IsMaster(), step.wantMaster)
+			}
+			if primary := sc.PrimaryElectionID(); !proto.Equal(primary, &v1.Uint128{Low: step.wantPrimary}) {
+				t.Errorf("PrimaryElectionID() = %v, want %d", primary, step.wantPrimary)
+			}
+
+			select {
+			case data := <-sc.MastershipChannel:
+				if !step.wantNotify {
+					t.Fatalf("MastershipChannel received %+v, want no notification", data)
+				}
+				if data.IsMaster != step.wantMaster || data.PrimaryElectionID.GetLow() != step.wantPrimary {
+					t.Errorf("MastershipChannel received %+v, want master %v with primary %d", data, step.wantMaster, step.wantPrimary)
+				}
+			default:
+				if step.wantNotify {
+					t.Fatal("MastershipChannel received nothing")
+				}
+			}
+		})
+	}
+
+	// 第一次仲裁完成的通知只保留一个，不会阻塞后续的仲裁结果
+	select {
+	case <-sc.setupNotifChannel:
+	default:
+		t.Error("setupNotifChannel was not notified")
+	}
+}
+
+func TestGuardedClientRefusesWritesWithoutMastership(t *testing.T) {
+	update := &v1.Update{Type: v1.Update_INSERT, Entity: &v1.Entity{Entity: &v1.Entity_TableEntry{TableEntry: &v1.TableEntry{TableId: 1}}}}
+	tests := []struct {
+		name       string
+		isMaster   bool
+		wantErr    error
+		wantWrites int
+	}{
+		{"primary", true, nil, 1},
+		{"backup", false, ErrNotPrimary, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeMasterClient{isMaster: tt.isMaster}
+			sc := newMastershipController(fake)
+			if err := sc.Client.WriteUpdate(update); !errors.Is(err, tt.wantErr) {
+				t.Errorf("WriteUpdate() error = %v, want %v", err, tt.wantErr)
+			}
+			batch := sc.Client.NewBatch(v1.WriteRequest_CONTINUE_ON_ERROR)
+			batch.WriteUpdate(update)
+			if err := batch.Send(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Batch.Send() error = %v, want %v", err, tt.wantErr)
+			}
+			if fake.writes != 2*tt.wantWrites {
+				t.Errorf("switch received %d writes, want %d", fake.writes, 2*tt.wantWrites)
+			}
+		})
+	}
+}
+
+func TestConnectionMonitorDropsMastership(t *testing.T) {
+	fake := &fakeMasterClient{isMaster: true, states: make(chan client.ConnectionState)}
+	sc := newMastershipController(fake)
+	sc.StartConnectionMonitor()
+
+	fake.states <- client.Connected
+	fake.states <- client.Disconnected
+	select {
+	case data := <-sc.MastershipChannel:
+		if data.IsMaster {
+			t.Errorf("MastershipChannel received %+v after disconnecting, want a demotion", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("MastershipChannel received nothing after disconnecting")
+	}
+	if sc.IsMaster() {
+		t.Error("IsMaster() = true after disconnecting")
+	}
+	close(fake.states)
+}
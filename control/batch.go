@@ -0,0 +1,23 @@
+package control
+
+import (
+	"context"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
+)
+
+// batchWriter 是 TableControl、DigestControl、ActionProfileControl 和 ReplicationControl 共用的写入逻辑：
+// 未设置批次时立即发送 update，设置批次后 update 累积到批次中，调用 batch.Send 时才会真正发送。
+type batchWriter struct {
+	control *Controller
+	batch   *client.Batch
+}
+
+// writeUpdate 发送 update，若设置了批次则加入批次
+func (bw batchWriter) writeUpdate(ctx context.Context, update *v1.Update) error {
+	if bw.batch != nil {
+		return bw.batch.WriteUpdate(update)
+	}
+	return bw.control.Client.WriteUpdateContext(ctx, update)
+}
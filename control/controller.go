@@ -1,9 +1,13 @@
 package control
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"sync"
 
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
 	"github.com/p4lang/p4runtime/go/p4/v1"
 	"p4r/client"
 	"p4r/entity"
@@ -13,18 +17,37 @@ import (
 //   - Client: P4RClient 实例，用于与 P4Runtime 交换机通信。
 //   - DigestChannel: 用于处理来自 P4 交换机的 Digest 消息的通道。
 //   - ArbitrationChannel: 用于处理仲裁消息的通道，用于管理控制器的主控权。
-//   - setupNotifChannel: 用于通知仲裁是否成功。
+//   - PacketInChannel: 用于接收交换机上送的 PacketIn 报文，元数据已按名称解码；通道已满时新报文会被丢弃。
+//   - IdleTimeoutChannel: 用于接收空闲超时的表项，表项已按名称解码；通道已满时新表项会被丢弃。
+//   - idleTimeoutQueue: 待处理的 IdleTimeoutNotification，由单个 goroutine 按到达顺序处理。
+//   - MastershipChannel: 用于接收主控权的变化，包括第一次仲裁的结果。
+//   - setupNotifChannel: 用于通知第一次仲裁已经完成。
+//   - DigestChannel 和订阅者的通道已满时新的 digest 消息会被丢弃，交换机会在 ack_timeout 后重发未确认的 DigestList。
+//   - digestSubscribers: 通过 DigestControl.Subscribe 订阅的 digest，按 digest ID 分发，未订阅的 digest 仍发送到 DigestChannel。
 type Controller struct {
 	Client             client.P4RClient
 	DigestChannel      chan *v1.StreamMessageResponse_Digest
 	ArbitrationChannel chan *v1.StreamMessageResponse_Arbitration
+	PacketInChannel    chan *PacketInData
+	IdleTimeoutChannel chan *IdleTimeoutData
+	idleTimeoutQueue   chan *v1.IdleTimeoutNotification
+	idleTimeoutPolicy  IdleTimeoutPolicy
+	idleTimeoutMu      sync.Mutex
+	MastershipChannel  chan *MastershipData
 	setupNotifChannel  chan bool
+	masterMu           sync.Mutex
+	arbitrated         bool
+	lastMaster         bool
+	primaryElectionID  *v1.Uint128
+	digestSubscribers  map[uint32]*digestSubscriber
+	digestMu           sync.RWMutex
 }
 
 // StartMessageRouter 该方法启动了一个 goroutine，监听 IncomingMessageChannel，
 // 并根据消息类型将消息发送到相应的处理通道（例如仲裁消息发送到 ArbitrationChannel，Digest 消息发送到 DigestChannel）。
 func (sc *Controller) StartMessageRouter() {
 	IncomingMessageChannel := sc.Client.GetMessageChannels().IncomingMessageChannel
+	go sc.processIdleTimeouts()
 	go func() {
 		for {
 			in := <-IncomingMessageChannel
@@ -34,7 +57,11 @@ func (sc *Controller) StartMessageRouter() {
 			case *v1.StreamMessageResponse_Arbitration:
 				sc.ArbitrationChannel <- update.(*v1.StreamMessageResponse_Arbitration)
 			case *v1.StreamMessageResponse_Digest:
-				sc.DigestChannel <- update.(*v1.StreamMessageResponse_Digest)
+				sc.routeDigest(update.(*v1.StreamMessageResponse_Digest))
+			case *v1.StreamMessageResponse_Packet:
+				sc.routePacketIn(update.(*v1.StreamMessageResponse_Packet).Packet)
+			case *v1.StreamMessageResponse_IdleTimeoutNotification:
+				sc.routeIdleTimeout(update.(*v1.StreamMessageResponse_IdleTimeoutNotification).IdleTimeoutNotification)
 			default:
 				log.Println("Message has unknown type")
 			}
@@ -42,11 +69,33 @@ func (sc *Controller) StartMessageRouter() {
 	}()
 }
 
+// routeDigest 将 digest 消息发送给对应的订阅者，没有订阅者时发送到 DigestChannel。
+// 通道已满时丢弃消息，避免订阅者处理缓慢或流断开时阻塞消息路由。
+func (sc *Controller) routeDigest(update *v1.StreamMessageResponse_Digest) {
+	sc.digestMu.RLock()
+	subscriber, ok := sc.digestSubscribers[update.Digest.DigestId]
+	sc.digestMu.RUnlock()
+
+	if ok {
+		select {
+		case subscriber.lists <- update.Digest:
+		default:
+			log.Println("Digest subscriber channel is full, dropping digest list", update.Digest.ListId)
+		}
+		return
+	}
+	select {
+	case sc.DigestChannel <- update:
+	default:
+		log.Println("Digest channel is full, dropping digest list", update.Digest.ListId)
+	}
+}
+
 // SetMastershipStatus 该方法设置控制器的主控权状态。
-// 调用 P4RClient 的 SetMastershipStatus 方法，并通过 setupNotifChannel 通知主控权状态的变化。
+// 调用 P4RClient 的 SetMastershipStatus 方法，状态发生变化时发送到 MastershipChannel。
 func (sc *Controller) SetMastershipStatus(status bool) {
-	sc.setupNotifChannel <- status
 	sc.Client.SetMastershipStatus(status)
+	sc.notifyMastership(&MastershipData{IsMaster: status})
 }
 
 func (sc *Controller) IsMaster() bool {
@@ -54,76 +103,261 @@ func (sc *Controller) IsMaster() bool {
 }
 
 // Run 方法是控制器启动的主要入口，负责运行一系列操作：
-//  1. 启动客户端。
-//  2. 启动消息路由。
-//  3. 启动仲裁更新监听。
-//  4. 执行仲裁以参与主控权竞争。
-//  5. 等待仲裁结果。
+//  1. 启动连接状态监听。
+//  2. 启动客户端。
+//  3. 启动消息路由。
+//  4. 启动仲裁更新监听。
+//  5. 执行仲裁以参与主控权竞争。
+//  6. 等待第一次仲裁结果，之后主控权的变化通过 MastershipChannel 通知。
 func (sc *Controller) Run() {
+	sc.StartConnectionMonitor()
 	sc.Client.Run()
 	sc.StartMessageRouter()
 	sc.StartArbitrationUpdateListener()
-	sc.PerformArbitration()
+	if err := sc.PerformArbitration(); err != nil {
+		log.Println("Unable to send arbitration request:", err)
+	}
 	<-sc.setupNotifChannel
 }
 
 // InstallProgram 该方法用于安装 P4 编译后的二进制程序到设备上。
 //   - 它首先检查是否拥有主控权，只有在成为主控设备时才能执行安装操作，否则会返回错误。
-func (sc *Controller) InstallProgram(binPath, p4InfoPath string) error {
+//   - opts 用于指定安装模式（见 client.WithPipelineAction）和 cookie，默认为 VERIFY_AND_COMMIT。
+func (sc *Controller) InstallProgram(binPath, p4InfoPath string, opts ...client.FwdPipeOption) error {
+	if !sc.IsMaster() {
+		return errors.New("Control does not have mastership, cannot install program on device")
+	}
+	return sc.Client.SetFwdPipe(binPath, p4InfoPath, opts...)
+}
+
+// InstallProgramConfig 与 InstallProgram 相同，但使用内存中的设备配置和已经解析的 P4Info
+func (sc *Controller) InstallProgramConfig(deviceConfig []byte, p4Info *configv1.P4Info, opts ...client.FwdPipeOption) error {
 	if !sc.IsMaster() {
 		return errors.New("Control does not have mastership, cannot install program on device")
 	}
-	return sc.Client.SetFwdPipe(binPath, p4InfoPath)
+	return sc.Client.SetFwdPipeConfig(context.Background(), deviceConfig, p4Info, opts...)
+}
+
+// CommitProgram 使之前以 VERIFY_AND_SAVE 模式安装的程序生效
+func (sc *Controller) CommitProgram() error {
+	if !sc.IsMaster() {
+		return errors.New("Control does not have mastership, cannot commit program on device")
+	}
+	return sc.Client.CommitFwdPipe()
+}
+
+// EnsureProgram 与 InstallProgram 类似，但交换机已经运行该程序（cookie 相同）时跳过安装，保留已有的转发状态。
+// 返回值表示是否进行了安装。
+func (sc *Controller) EnsureProgram(binPath, p4InfoPath string, opts ...client.FwdPipeOption) (bool, error) {
+	if !sc.IsMaster() {
+		return false, errors.New("Control does not have mastership, cannot install program on device")
+	}
+	return sc.Client.EnsureFwdPipe(binPath, p4InfoPath, opts...)
 }
 
-func NewController(addr string, deviceID uint64, electionID v1.Uint128) (Control, error) {
-	Client, err := client.NewClient(addr, deviceID, electionID)
+// EnsureProgramConfig 与 EnsureProgram 相同，但使用内存中的设备配置和已经解析的 P4Info
+func (sc *Controller) EnsureProgramConfig(deviceConfig []byte, p4Info *configv1.P4Info, opts ...client.FwdPipeOption) (bool, error) {
+	if !sc.IsMaster() {
+		return false, errors.New("Control does not have mastership, cannot install program on device")
+	}
+	return sc.Client.EnsureFwdPipeConfig(context.Background(), deviceConfig, p4Info, opts...)
+}
+
+// AttachProgram 从交换机读取已经安装的程序的 P4Info，用于接管正在运行的流水线而不重新安装。
+// 不需要主控权，备用控制器也可以据此读取表项等状态。
+func (sc *Controller) AttachProgram() error {
+	_, err := sc.Client.GetFwdPipe(v1.GetForwardingPipelineConfigRequest_P4INFO_AND_COOKIE)
+	return err
+}
+
+// logStreamError 在读取结束后记录错误通道中的错误，用于不返回错误通道的流式读取方法
+func logStreamError(errChannel <-chan error) {
+	go func() {
+		if err := <-errChannel; err != nil {
+			log.Println("Read stream ended with error:", err)
+		}
+	}()
+}
+
+// NewController 创建控制器，opts 会传给 client.NewClient，用于配置 TLS 等连接参数。
+// electionID 与 client.Client.Init 一样使用指针，避免复制 protobuf 消息。
+func NewController(addr string, deviceID uint64, electionID *v1.Uint128, opts ...client.Option) (Control, error) {
+	Client, err := client.NewClient(addr, deviceID, electionID, opts...)
 	if err != nil {
 		return nil, err
 	}
 	digestChan := make(chan *v1.StreamMessageResponse_Digest, 10)
 	arbitrationChan := make(chan *v1.StreamMessageResponse_Arbitration)
-	setupNotifChan := make(chan bool)
+	packetInChan := make(chan *PacketInData, 100)
+	idleTimeoutChan := make(chan *IdleTimeoutData, 100)
+	idleTimeoutQueue := make(chan *v1.IdleTimeoutNotification, 100)
+	mastershipChan := make(chan *MastershipData, 10)
+	setupNotifChan := make(chan bool, 1)
 
 	controller := Controller{
-		Client:             Client,
 		DigestChannel:      digestChan,
 		ArbitrationChannel: arbitrationChan,
+		PacketInChannel:    packetInChan,
+		IdleTimeoutChannel: idleTimeoutChan,
+		idleTimeoutQueue:   idleTimeoutQueue,
+		MastershipChannel:  mastershipChan,
 		setupNotifChannel:  setupNotifChan,
+		digestSubscribers:  make(map[uint32]*digestSubscriber),
 	}
+	controller.Client = guardedClient{P4RClient: Client, control: &controller}
 
 	return &controller, nil
 }
 
-// Table 返回 TableControl
-func (sc *Controller) Table(tableName string) TableControl {
-	tables := *sc.Client.GetEntities("TABLE")
-	table := tables[tableName].(*entity.Table)
+// lookup 通过 P4Info 索引按完整名称或别名查找实体
+func (sc *Controller) lookup(entityType, name string) (entity.Entity, error) {
+	return sc.Client.Index().Lookup(entityType, name)
+}
 
-	return TableControl{
-		table:   table,
-		control: sc,
+// Table 返回 TableControl，名称可以是完整名称或别名。
+// 尚未安装程序时返回 ErrPipelineNotInstalled，找不到表时返回 ErrUnknownEntity。
+func (sc *Controller) Table(tableName string) (TableControl, error) {
+	e, err := sc.lookup("TABLE", tableName)
+	if err != nil {
+		return TableControl{}, err
 	}
+	table, ok := e.(*entity.Table)
+	if !ok {
+		return TableControl{}, fmt.Errorf("%w: %s is not a table", ErrUnknownEntity, tableName)
+	}
+
+	return TableControl{
+		batchWriter: batchWriter{control: sc},
+		table:       table,
+	}, nil
 }
 
-// Digest 返回 DigestControl
-func (sc *Controller) Digest(digestName string) DigestControl {
-	digests := *sc.Client.GetEntities("DIGEST")
-	digest := digests[digestName].(*entity.Digest)
+// MustTable 与 Table 相同，但出错时 panic
+func (sc *Controller) MustTable(tableName string) TableControl {
+	return must(sc.Table(tableName))
+}
+
+// Digest 返回 DigestControl，名称可以是完整名称或别名，错误与 Table 相同
+func (sc *Controller) Digest(digestName string) (DigestControl, error) {
+	e, err := sc.lookup("DIGEST", digestName)
+	if err != nil {
+		return DigestControl{}, err
+	}
+	digest, ok := e.(*entity.Digest)
+	if !ok {
+		return DigestControl{}, fmt.Errorf("%w: %s is not a digest", ErrUnknownEntity, digestName)
+	}
 
 	return DigestControl{
-		digest:  digest,
+		batchWriter: batchWriter{control: sc},
+		digest:      digest,
+	}, nil
+}
+
+// MustDigest 与 Digest 相同，但出错时 panic
+func (sc *Controller) MustDigest(digestName string) DigestControl {
+	return must(sc.Digest(digestName))
+}
+
+// Meter 返回 MeterControl，名称可以是完整名称或别名，错误与 Table 相同
+func (sc *Controller) Meter(meterName string) (MeterControl, error) {
+	e, err := sc.lookup("METER", meterName)
+	if err != nil {
+		return MeterControl{}, err
+	}
+	meter, ok := e.(*entity.Meter)
+	if !ok {
+		return MeterControl{}, fmt.Errorf("%w: %s is not a meter", ErrUnknownEntity, meterName)
+	}
+
+	return MeterControl{
+		meter:   meter,
 		control: sc,
+	}, nil
+}
+
+// MustMeter 与 Meter 相同，但出错时 panic
+func (sc *Controller) MustMeter(meterName string) MeterControl {
+	return must(sc.Meter(meterName))
+}
+
+// Register 返回 RegisterControl，名称可以是完整名称或别名，错误与 Table 相同
+func (sc *Controller) Register(registerName string) (RegisterControl, error) {
+	e, err := sc.lookup("REGISTER", registerName)
+	if err != nil {
+		return RegisterControl{}, err
+	}
+	register, ok := e.(*entity.Register)
+	if !ok {
+		return RegisterControl{}, fmt.Errorf("%w: %s is not a register", ErrUnknownEntity, registerName)
 	}
+
+	return RegisterControl{
+		register: register,
+		control:  sc,
+	}, nil
 }
 
-// Counter 返回 CounterControl
-func (sc *Controller) Counter(counterName string) CounterControl {
-	counters := *sc.Client.GetEntities("COUNTER")
-	counter := counters[counterName].(*entity.Counter)
+// MustRegister 与 Register 相同，但出错时 panic
+func (sc *Controller) MustRegister(registerName string) RegisterControl {
+	return must(sc.Register(registerName))
+}
+
+// ActionProfile 返回 ActionProfileControl，名称可以是完整名称或别名，错误与 Table 相同
+func (sc *Controller) ActionProfile(actionProfileName string) (ActionProfileControl, error) {
+	e, err := sc.lookup("ACTION_PROFILE", actionProfileName)
+	if err != nil {
+		return ActionProfileControl{}, err
+	}
+	actionProfile, ok := e.(*entity.ActionProfile)
+	if !ok {
+		return ActionProfileControl{}, fmt.Errorf("%w: %s is not an action profile", ErrUnknownEntity, actionProfileName)
+	}
+
+	return ActionProfileControl{
+		batchWriter:   batchWriter{control: sc},
+		actionProfile: actionProfile,
+	}, nil
+}
+
+// MustActionProfile 与 ActionProfile 相同，但出错时 panic
+func (sc *Controller) MustActionProfile(actionProfileName string) ActionProfileControl {
+	return must(sc.ActionProfile(actionProfileName))
+}
+
+// Replication 返回 ReplicationControl
+func (sc *Controller) Replication() ReplicationControl {
+	return ReplicationControl{
+		batchWriter: batchWriter{control: sc},
+	}
+}
+
+// Counter 返回 CounterControl，名称可以是完整名称或别名，错误与 Table 相同
+func (sc *Controller) Counter(counterName string) (CounterControl, error) {
+	e, err := sc.lookup("COUNTER", counterName)
+	if err != nil {
+		return CounterControl{}, err
+	}
+	counter, ok := e.(*entity.Counter)
+	if !ok {
+		return CounterControl{}, fmt.Errorf("%w: %s is not a counter", ErrUnknownEntity, counterName)
+	}
 
 	return CounterControl{
 		counter: counter,
 		control: sc,
+	}, nil
+}
+
+// MustCounter 与 Counter 相同，但出错时 panic
+func (sc *Controller) MustCounter(counterName string) CounterControl {
+	return must(sc.Counter(counterName))
+}
+
+// must 在 err 不为 nil 时 panic，用于实现 Must 系列方法
+func must[T any](control T, err error) T {
+	if err != nil {
+		panic(err)
 	}
+	return control
 }
@@ -1,6 +1,7 @@
 package control
 
 import (
+	"context"
 	"errors"
 
 	"github.com/p4lang/p4runtime/go/p4/v1"
@@ -27,10 +28,15 @@ func getCounterData(entity *v1.Entity) CounterData {
 // ReadValueAtIndex 方法用于读取计数器在指定索引处的值。
 // 它通过索引读取 entity 并调用客户端同步读取方法 ReadEntitiesSync，返回一个 CounterData 实例。
 func (cc *CounterControl) ReadValueAtIndex(index int64) (*CounterData, error) {
+	return cc.ReadValueAtIndexContext(context.Background(), index)
+}
+
+// ReadValueAtIndexContext 与 ReadValueAtIndex 相同，但使用 ctx 控制请求的截止时间和取消
+func (cc *CounterControl) ReadValueAtIndexContext(ctx context.Context, index int64) (*CounterData, error) {
 	Entity := cc.counter.ReadValueWithIndex(index)
 	entityList := []*v1.Entity{Entity}
 
-	res, err := cc.control.Client.ReadEntitiesSync(entityList)
+	res, err := cc.control.Client.ReadEntitiesSyncContext(ctx, entityList)
 	if err != nil {
 		return nil, err
 	}
@@ -44,10 +50,15 @@ func (cc *CounterControl) ReadValueAtIndex(index int64) (*CounterData, error) {
 
 // ReadValues 该方法用于读取计数器的所有值。它调用 ReadEntitiesSync，读取并返回计数器的所有条目。
 func (cc *CounterControl) ReadValues() ([]*CounterData, error) {
+	return cc.ReadValuesContext(context.Background())
+}
+
+// ReadValuesContext 与 ReadValues 相同，但使用 ctx 控制请求的截止时间和取消
+func (cc *CounterControl) ReadValuesContext(ctx context.Context) ([]*CounterData, error) {
 	entity := cc.counter.ReadValue()
 	entityList := []*v1.Entity{entity}
 
-	res, err := cc.control.Client.ReadEntitiesSync(entityList)
+	res, err := cc.control.Client.ReadEntitiesSyncContext(ctx, entityList)
 
 	if err != nil {
 		return nil, err
@@ -70,23 +81,45 @@ func (cc *CounterControl) ReadValues() ([]*CounterData, error) {
 
 // StreamValues 该方法用于异步读取计数器的所有值，并将结果通过通道 (channel) 发送出去。它适用于需要异步操作的场景。
 // 一个 goroutine 被启动，用于从 counterEntityCh 读取数据，并将其转换为 CounterData 后发送到通道 cdataChannel 中。
+// 读取中断时通道同样会被关闭，错误只记录日志，需要获取错误时使用 StreamValuesContext。
 func (cc *CounterControl) StreamValues() (chan *CounterData, error) {
+	cdataChannel, errChannel, err := cc.StreamValuesContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	logStreamError(errChannel)
+	return cdataChannel, nil
+}
+
+// StreamValuesContext 与 StreamValues 相同，但使用 ctx 控制读取的截止时间和取消。
+// 数据通道关闭后，错误通道会收到读取结束的原因，正常结束时为 nil。
+func (cc *CounterControl) StreamValuesContext(ctx context.Context) (chan *CounterData, <-chan error, error) {
 	entity := cc.counter.ReadValue()
 	entityList := []*v1.Entity{entity}
 
-	counterEntityCh, err := cc.control.Client.ReadEntities(entityList)
+	counterEntityCh, readErrCh, err := cc.control.Client.ReadEntitiesContext(ctx, entityList)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	cdataChannel := make(chan *CounterData, cc.counter.Size)
+	errChannel := make(chan error, 1)
 	go func() {
-		defer close(cdataChannel)
-		for e := range counterEntityCh {
-			counterData := getCounterData(e)
-			cdataChannel <- &counterData
-		}
+		err := func() error {
+			for e := range counterEntityCh {
+				counterData := getCounterData(e)
+				select {
+				case cdataChannel <- &counterData:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return <-readErrCh
+		}()
+		close(cdataChannel)
+		errChannel <- err
+		close(errChannel)
 	}()
 
-	return cdataChannel, nil
+	return cdataChannel, errChannel, nil
 }
@@ -1,15 +1,25 @@
 package control
 
 import (
+	"context"
+	"log"
+
 	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
 	"p4r/entity"
 )
 
 // DigestControl 用于在控制器中处理 P4 中的 DigestEntries。
 // 主要功能包括在交换机中插入、修改、删除 DigestEntries 以及向交换机确认接收到的 Digest 消息。
 type DigestControl struct {
-	control *Controller
-	digest  *entity.Digest
+	batchWriter
+	digest *entity.Digest
+}
+
+// WithBatch 返回一个写操作累积到 batch 中的 DigestControl，调用 batch.Send 时才会真正发送
+func (dc DigestControl) WithBatch(batch *client.Batch) DigestControl {
+	dc.batch = batch
+	return dc
 }
 
 func (dc *DigestControl) getDigestEntryConfig(maxListSize int32, maxTimeoutNs, ackTimeoutNs int64) *v1.DigestEntry {
@@ -25,28 +35,114 @@ func (dc *DigestControl) getDigestEntryConfig(maxListSize int32, maxTimeoutNs, a
 
 // Insert 向交换机插入新的 DigestEntry
 func (dc DigestControl) Insert(maxListSize int32, maxTimeoutNs, ackTimeoutNs int64) error {
+	return dc.InsertContext(context.Background(), maxListSize, maxTimeoutNs, ackTimeoutNs)
+}
+
+// InsertContext 与 Insert 相同，但使用 ctx 控制请求的截止时间和取消
+func (dc DigestControl) InsertContext(ctx context.Context, maxListSize int32, maxTimeoutNs, ackTimeoutNs int64) error {
 	entry := dc.getDigestEntryConfig(maxListSize, maxTimeoutNs, ackTimeoutNs)
 	update := dc.digest.Insert(entry)
-	return dc.control.Client.WriteUpdate(update)
+	return dc.writeUpdate(ctx, update)
 }
 
 // Modify 修改交换机上的现有 DigestEntry
 func (dc DigestControl) Modify(maxListSize int32, maxTimeoutNs, ackTimeoutNs int64) error {
+	return dc.ModifyContext(context.Background(), maxListSize, maxTimeoutNs, ackTimeoutNs)
+}
+
+// ModifyContext 与 Modify 相同，但使用 ctx 控制请求的截止时间和取消
+func (dc DigestControl) ModifyContext(ctx context.Context, maxListSize int32, maxTimeoutNs, ackTimeoutNs int64) error {
 	entry := dc.getDigestEntryConfig(maxListSize, maxTimeoutNs, ackTimeoutNs)
 	update := dc.digest.Modify(entry)
-	return dc.control.Client.WriteUpdate(update)
+	return dc.writeUpdate(ctx, update)
 }
 
 // Delete 删除交换机中的 DigestEntry，表示控制器不再接收对应的 Digest 消息。
 func (dc DigestControl) Delete() error {
+	return dc.DeleteContext(context.Background())
+}
+
+// DeleteContext 与 Delete 相同，但使用 ctx 控制请求的截止时间和取消
+func (dc DigestControl) DeleteContext(ctx context.Context) error {
 	update := dc.digest.Delete()
-	return dc.control.Client.WriteUpdate(update)
+	return dc.writeUpdate(ctx, update)
 
 }
 
-// Acknowledge 用于确认控制器已经收到一个 DigestList。通过向 OutgoingMessageChannel 发送确认消息通知交换机。
-func (dc DigestControl) Acknowledge(digestList *v1.DigestList) {
+// Acknowledge 用于确认控制器已经收到一个 DigestList。通过 StreamChannel 发送确认消息通知交换机，
+// 发送缓冲区已满或客户端已关闭时返回错误，未确认的 DigestList 会由交换机在 ack_timeout 后重发。
+func (dc DigestControl) Acknowledge(digestList *v1.DigestList) error {
 	message := dc.digest.Acknowledge(digestList)
-	reqChannel := dc.control.Client.GetMessageChannels().OutgoingMessageChannel
-	reqChannel <- message
+	return dc.control.Client.SendMessage(message)
+}
+
+// DigestHandler 处理一条解码后的 digest 数据，values 以 digest 成员名称为键，值的类型见 entity.Digest.Decode。
+type DigestHandler func(values map[string]interface{})
+
+// Subscribe 订阅该 digest：每收到一个 DigestList，将其中的每条数据解码后交给 handler，
+// handler 处理完整个 DigestList 后自动向交换机发送确认。
+// 订阅后该 digest 的消息不再发送到 Controller.DigestChannel；重复订阅会替换之前的 handler。
+func (dc DigestControl) Subscribe(handler DigestHandler) {
+	dc.subscribe(func(data *v1.P4Data) error {
+		values, err := dc.digest.Decode(data)
+		if err != nil {
+			return err
+		}
+		handler(values)
+		return nil
+	})
+}
+
+// SubscribeStruct 与 DigestControl.Subscribe 类似，但将每条数据解码到类型为 T 的结构体中，
+// 结构体字段通过 `p4:"member_name"` 标签与 digest 成员对应，标签格式见 entity.Digest.DecodeInto。
+func SubscribeStruct[T any](dc DigestControl, handler func(*T)) {
+	dc.subscribe(func(data *v1.P4Data) error {
+		value := new(T)
+		if err := dc.digest.DecodeInto(data, value); err != nil {
+			return err
+		}
+		handler(value)
+		return nil
+	})
+}
+
+// digestSubscriber 是一个 digest 的订阅：lists 接收路由过来的 DigestList，
+// 订阅被替换时关闭 done 通知处理 goroutine 退出。lists 不会被关闭，路由时不需要持有锁。
+type digestSubscriber struct {
+	lists chan *v1.DigestList
+	done  chan struct{}
+}
+
+// subscribe 注册订阅通道并启动处理 goroutine，解码失败的数据会被记录并跳过。
+func (dc DigestControl) subscribe(process func(*v1.P4Data) error) {
+	subscriber := &digestSubscriber{
+		lists: make(chan *v1.DigestList, 10),
+		done:  make(chan struct{}),
+	}
+
+	sc := dc.control
+	sc.digestMu.Lock()
+	if previous, ok := sc.digestSubscribers[dc.digest.ID]; ok {
+		close(previous.done)
+	}
+	sc.digestSubscribers[dc.digest.ID] = subscriber
+	sc.digestMu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case digestList := <-subscriber.lists:
+				for _, data := range digestList.Data {
+					if err := process(data); err != nil {
+						log.Println("Unable to decode digest data:", err)
+					}
+				}
+				if err := dc.Acknowledge(digestList); err != nil {
+					log.Println("Unable to acknowledge digest list:", err)
+				}
+			case <-subscriber.done:
+				return
+			}
+		}
+	}()
 }
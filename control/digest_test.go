@@ -0,0 +1,46 @@
+package control
+
+import (
+	"testing"
+	"time"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+func TestRouteDigestDoesNotBlock(t *testing.T) {
+	subscriber := &digestSubscriber{lists: make(chan *v1.DigestList, 1), done: make(chan struct{})}
+	sc := &Controller{
+		DigestChannel:     make(chan *v1.StreamMessageResponse_Digest),
+		digestSubscribers: map[uint32]*digestSubscriber{1: subscriber},
+	}
+	digest := func(id uint32, listID uint64) *v1.StreamMessageResponse_Digest {
+		return &v1.StreamMessageResponse_Digest{Digest: &v1.DigestList{DigestId: id, ListId: listID}}
+	}
+
+	tests := []struct {
+		name   string
+		update *v1.StreamMessageResponse_Digest
+	}{
+		{"subscriber has room", digest(1, 1)},
+		{"subscriber channel is full", digest(1, 2)},
+		{"nobody reads DigestChannel", digest(2, 3)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routed := make(chan struct{})
+			go func() {
+				sc.routeDigest(tt.update)
+				close(routed)
+			}()
+			select {
+			case <-routed:
+			case <-time.After(time.Second):
+				t.Fatal("routeDigest() blocked")
+			}
+		})
+	}
+
+	if got := <-subscriber.lists; got.ListId != 1 {
+		t.Errorf("subscriber received list %d, want 1", got.ListId)
+	}
+}
@@ -1,25 +1,35 @@
 package control
 
 import (
+	"context"
 	"errors"
+	"log"
 
 	"github.com/p4lang/p4runtime/go/p4/v1"
 	"p4r/client"
 	"p4r/entity"
 )
 
-func getDirectCounterData(entity *v1.Entity) DirectCounterData {
-	dcEntry := entity.GetDirectCounterEntry()
-	tableEntry := TableEntry(*dcEntry.TableEntry)
-	return DirectCounterData{
-		TableEntry:  &tableEntry,
-		ByteCount:   dcEntry.Data.ByteCount,
-		PacketCount: dcEntry.Data.PacketCount,
+// getDirectCounterData 从 v1.Entity 中提取 DirectCounter 数据，并按 table 的 P4Info 解码表项的匹配字段
+func getDirectCounterData(table *entity.Table, e *v1.Entity) (*DirectCounterData, error) {
+	dcEntry := e.GetDirectCounterEntry()
+	if dcEntry == nil {
+		return nil, errors.New("Entity is not a direct counter entry")
 	}
+	matches, err := table.DecodeMatches(dcEntry.TableEntry.GetMatch())
+	if err != nil {
+		return nil, err
+	}
+	return &DirectCounterData{
+		TableEntry:  (*TableEntry)(dcEntry.TableEntry),
+		Matches:     matches,
+		ByteCount:   dcEntry.Data.GetByteCount(),
+		PacketCount: dcEntry.Data.GetPacketCount(),
+	}, nil
 }
 
-func getMultipleDCValuesSync(c client.P4RClient, req []*v1.Entity) ([]*DirectCounterData, error) {
-	res, err := c.ReadEntitiesSync(req)
+func getMultipleDCValuesSync(ctx context.Context, c client.P4RClient, table *entity.Table, req []*v1.Entity) ([]*DirectCounterData, error) {
+	res, err := c.ReadEntitiesSyncContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -29,58 +39,101 @@ func getMultipleDCValuesSync(c client.P4RClient, req []*v1.Entity) ([]*DirectCou
 		if item == nil {
 			continue
 		}
-		dcData := getDirectCounterData(item)
-		result = append(result, &dcData)
+		dcData, err := getDirectCounterData(table, item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, dcData)
 	}
 	return result, nil
 }
 
-func streamMultipleDCValues(c client.P4RClient, req []*v1.Entity) (chan *DirectCounterData, error) {
-	dcCounterEntityCh, err := c.ReadEntities(req)
+// streamMultipleDCValues 异步读取 DirectCounter 数据，解码失败的数据会被记录并跳过。
+// 数据通道关闭后，错误通道会收到读取结束的原因，正常结束时为 nil。
+func streamMultipleDCValues(ctx context.Context, c client.P4RClient, table *entity.Table, req []*v1.Entity) (chan *DirectCounterData, <-chan error, error) {
+	dcCounterEntityCh, readErrCh, err := c.ReadEntitiesContext(ctx, req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	dcDataChannel := make(chan *DirectCounterData, 100)
+	errChannel := make(chan error, 1)
 	go func() {
-		defer close(dcDataChannel)
-		for e := range dcCounterEntityCh {
-			dcCounterData := getDirectCounterData(e)
-			dcDataChannel <- &dcCounterData
-		}
+		err := func() error {
+			for e := range dcCounterEntityCh {
+				dcCounterData, err := getDirectCounterData(table, e)
+				if err != nil {
+					log.Println("Unable to decode direct counter entry:", err)
+					continue
+				}
+				select {
+				case dcDataChannel <- dcCounterData:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return <-readErrCh
+		}()
+		close(dcDataChannel)
+		errChannel <- err
+		close(errChannel)
 	}()
 
-	return dcDataChannel, nil
+	return dcDataChannel, errChannel, nil
 }
 
 // ReadDirectCounterValueOnEntry 从一个匹配的表项中读取 DirectCounter 值
-func (tc TableControl) ReadDirectCounterValueOnEntry(matches []entity.Match) (*DirectCounterData, error) {
-	entity := tc.table.DirectCounterForTableEntry(matches)
+func (tc TableControl) ReadDirectCounterValueOnEntry(matches map[string]entity.Match) (*DirectCounterData, error) {
+	return tc.ReadDirectCounterValueOnEntryContext(context.Background(), matches)
+}
+
+// ReadDirectCounterValueOnEntryContext 与 ReadDirectCounterValueOnEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ReadDirectCounterValueOnEntryContext(ctx context.Context, matches map[string]entity.Match) (*DirectCounterData, error) {
+	entity, err := tc.table.DirectCounterForTableEntry(matches)
+	if err != nil {
+		return nil, err
+	}
 	entityList := []*v1.Entity{entity}
 
-	res, err := tc.control.Client.ReadEntitiesSync(entityList)
+	res, err := tc.control.Client.ReadEntitiesSyncContext(ctx, entityList)
 	if err != nil {
 		return nil, err
 	}
 	if len(res) == 0 {
 		return nil, errors.New("No counter entries found")
 	}
-	result := getDirectCounterData(res[1])
-	return &result, nil
+	return getDirectCounterData(tc.table, res[0])
 }
 
 // ReadDirectCounterValuesSync 同步读取表中所有条目的 DirectCounter 数据。
 func (tc TableControl) ReadDirectCounterValuesSync() ([]*DirectCounterData, error) {
+	return tc.ReadDirectCounterValuesSyncContext(context.Background())
+}
+
+// ReadDirectCounterValuesSyncContext 与 ReadDirectCounterValuesSync 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ReadDirectCounterValuesSyncContext(ctx context.Context) ([]*DirectCounterData, error) {
 	entity := tc.table.AllDirectCountersForTable()
 	entityList := []*v1.Entity{entity}
 
-	return getMultipleDCValuesSync(tc.control.Client, entityList)
+	return getMultipleDCValuesSync(ctx, tc.control.Client, tc.table, entityList)
 }
 
 // StreamDirectCounterValues 该方法与 ReadDirectCounterValuesSync 类似，但它返回一个 channel，允许异步处理所有 DirectCounter 值。
+// 读取中断时通道同样会被关闭，错误只记录日志，需要获取错误时使用 StreamDirectCounterValuesContext。
 func (tc TableControl) StreamDirectCounterValues() (chan *DirectCounterData, error) {
+	dcDataChannel, errChannel, err := tc.StreamDirectCounterValuesContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	logStreamError(errChannel)
+	return dcDataChannel, nil
+}
+
+// StreamDirectCounterValuesContext 与 StreamDirectCounterValues 相同，但使用 ctx 控制读取的截止时间和取消，
+// 并通过错误通道返回读取结束的原因。
+func (tc TableControl) StreamDirectCounterValuesContext(ctx context.Context) (chan *DirectCounterData, <-chan error, error) {
 	entity := tc.table.AllDirectCountersForTable()
 	entityList := []*v1.Entity{entity}
 
-	return streamMultipleDCValues(tc.control.Client, entityList)
+	return streamMultipleDCValues(ctx, tc.control.Client, tc.table, entityList)
 }
@@ -0,0 +1,102 @@
+package control
+
+import (
+	"errors"
+	"log"
+	"math"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// CompareElectionID 比较两个选举 ID，a < b 时返回 -1，a == b 时返回 0，a > b 时返回 1。nil 视为 0。
+func CompareElectionID(a, b *v1.Uint128) int {
+	switch {
+	case a.GetHigh() < b.GetHigh():
+		return -1
+	case a.GetHigh() > b.GetHigh():
+		return 1
+	case a.GetLow() < b.GetLow():
+		return -1
+	case a.GetLow() > b.GetLow():
+		return 1
+	default:
+		return 0
+	}
+}
+
+// NextElectionID 返回比 electionID 大 1 的选举 ID，electionID 已是最大值时返回错误
+func NextElectionID(electionID *v1.Uint128) (*v1.Uint128, error) {
+	high, low := electionID.GetHigh(), electionID.GetLow()
+	if low < math.MaxUint64 {
+		return &v1.Uint128{High: high, Low: low + 1}, nil
+	}
+	if high < math.MaxUint64 {
+		return &v1.Uint128{High: high + 1, Low: 0}, nil
+	}
+	return nil, errors.New("election ID is already the maximum value")
+}
+
+// PrevElectionID 返回比 electionID 小 1 的选举 ID。
+// 0 表示客户端永远不会成为主控制器，因此结果为 0（即 electionID 不大于 1）时返回错误。
+func PrevElectionID(electionID *v1.Uint128) (*v1.Uint128, error) {
+	high, low := electionID.GetHigh(), electionID.GetLow()
+	if high == 0 && low <= 1 {
+		return nil, errors.New("no non-zero election ID is below the given one")
+	}
+	if low > 0 {
+		return &v1.Uint128{High: high, Low: low - 1}, nil
+	}
+	return &v1.Uint128{High: high - 1, Low: math.MaxUint64}, nil
+}
+
+// SetElectionID 修改控制器的选举 ID 并重新参与仲裁，仲裁结果通过 MastershipChannel 通知
+func (sc *Controller) SetElectionID(electionID *v1.Uint128) {
+	sc.Client.SetElectionID(electionID)
+	if err := sc.PerformArbitration(); err != nil {
+		log.Println("Unable to send arbitration request:", err)
+	}
+}
+
+// PrimaryElectionID 返回交换机在最近一次仲裁结果中报告的主控制器选举 ID，没有主控制器时为 nil
+func (sc *Controller) PrimaryElectionID() *v1.Uint128 {
+	sc.masterMu.Lock()
+	defer sc.masterMu.Unlock()
+	return sc.primaryElectionID
+}
+
+// YieldMastership 主动放弃主控权：将选举 ID 设为比交换机报告的当前主控制器选举 ID 小 1 的值并重新仲裁，
+// 仲裁结果通过 MastershipChannel 通知。同一设备和角色的选举 ID 必须唯一，
+// 紧邻主控制器的 ID 不会与主控制器冲突，而固定的值可能与其他备份控制器相同。
+// 交换机把主控权交给选举 ID 最高的控制器，其他备份控制器的选举 ID 都低于新的选举 ID 时本控制器仍是主控制器。
+// 交换机没有报告主控制器或主控制器的选举 ID 不大于 1 时返回错误，选举 ID 保持不变。
+// 之后可以通过 SetElectionID 或 TakeOver 重新竞争。
+func (sc *Controller) YieldMastership() error {
+	primary := sc.PrimaryElectionID()
+	if primary == nil {
+		return errors.New("the switch has not reported a primary controller")
+	}
+	electionID, err := PrevElectionID(primary)
+	if err != nil {
+		return err
+	}
+	sc.SetElectionID(electionID)
+	return nil
+}
+
+// TakeOver 使用比当前主控制器更高的选举 ID 重新仲裁以取得主控权，用于主备切换。
+// 控制器已经是主控制器时不做任何操作；交换机没有报告主控制器时使用 1 和当前选举 ID 中较大的一个。
+func (sc *Controller) TakeOver() error {
+	if sc.IsMaster() {
+		return nil
+	}
+
+	electionID, err := NextElectionID(sc.PrimaryElectionID())
+	if err != nil {
+		return err
+	}
+	if current := sc.Client.ElectionID(); CompareElectionID(current, electionID) > 0 {
+		electionID = current
+	}
+	sc.SetElectionID(electionID)
+	return nil
+}
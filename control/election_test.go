@@ -0,0 +1,98 @@
+package control
+
+import (
+	"math"
+	"testing"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+	"p4r/client"
+)
+
+// fakeArbitrationClient 记录选举 ID 和发送的仲裁请求
+type fakeArbitrationClient struct {
+	client.P4RClient
+	electionID *v1.Uint128
+	sent       []*v1.StreamMessageRequest
+}
+
+func (f *fakeArbitrationClient) ElectionID() *v1.Uint128 {
+	return f.electionID
+}
+
+func (f *fakeArbitrationClient) SetElectionID(electionID *v1.Uint128) {
+	f.electionID = electionID
+}
+
+func (f *fakeArbitrationClient) GetArbitrationData() client.ArbitrationData {
+	return client.ArbitrationData{DeviceID: 1, ElectionID: f.electionID}
+}
+
+func (f *fakeArbitrationClient) SendMessage(message *v1.StreamMessageRequest) error {
+	f.sent = append(f.sent, message)
+	return nil
+}
+
+func TestPrevElectionID(t *testing.T) {
+	tests := []struct {
+		name       string
+		electionID *v1.Uint128
+		want       *v1.Uint128
+		wantErr    bool
+	}{
+		{"low word", &v1.Uint128{Low: 10}, &v1.Uint128{Low: 9}, false},
+		{"borrow from high word", &v1.Uint128{High: 1}, &v1.Uint128{Low: math.MaxUint64}, false},
+		{"high and low words", &v1.Uint128{High: 2, Low: 5}, &v1.Uint128{High: 2, Low: 4}, false},
+		{"one", &v1.Uint128{Low: 1}, nil, true},
+		{"zero", &v1.Uint128{}, nil, true},
+		{"nil", nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PrevElectionID(tt.electionID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PrevElectionID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("PrevElectionID() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestYieldMastership(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary *v1.Uint128
+		want    *v1.Uint128
+		wantErr bool
+	}{
+		{"just below the primary", &v1.Uint128{High: 1, Low: 20}, &v1.Uint128{High: 1, Low: 19}, false},
+		{"no primary reported", nil, nil, true},
+		{"primary election ID is 1", &v1.Uint128{Low: 1}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := &v1.Uint128{High: 1, Low: 20}
+			fake := &fakeArbitrationClient{electionID: current}
+			sc := &Controller{Client: fake, primaryElectionID: tt.primary}
+
+			err := sc.YieldMastership()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("YieldMastership() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if fake.electionID != current || len(fake.sent) != 0 {
+					t.Errorf("YieldMastership() changed the election ID to %v or sent %d requests", fake.electionID, len(fake.sent))
+				}
+				return
+			}
+			if !proto.Equal(fake.electionID, tt.want) {
+				t.Errorf("election ID = %v, want %v", fake.electionID, tt.want)
+			}
+			if len(fake.sent) != 1 || !proto.Equal(fake.sent[0].GetArbitration().GetElectionId(), tt.want) {
+				t.Errorf("sent %v, want one arbitration request with election ID %v", fake.sent, tt.want)
+			}
+		})
+	}
+}
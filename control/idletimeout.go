@@ -0,0 +1,75 @@
+package control
+
+import (
+	"log"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// SetIdleTimeoutPolicy 设置收到 IdleTimeoutNotification 后对超时表项的处理策略，默认为 IdleTimeoutNotify。
+// 可以在运行过程中调用，新的策略从下一个超时表项开始生效。
+func (sc *Controller) SetIdleTimeoutPolicy(policy IdleTimeoutPolicy) {
+	sc.idleTimeoutMu.Lock()
+	defer sc.idleTimeoutMu.Unlock()
+	sc.idleTimeoutPolicy = policy
+}
+
+// currentIdleTimeoutPolicy 返回当前的超时表项处理策略
+func (sc *Controller) currentIdleTimeoutPolicy() IdleTimeoutPolicy {
+	sc.idleTimeoutMu.Lock()
+	defer sc.idleTimeoutMu.Unlock()
+	return sc.idleTimeoutPolicy
+}
+
+// routeIdleTimeout 将通知加入 idleTimeoutQueue，队列已满时丢弃通知，避免阻塞消息路由。
+func (sc *Controller) routeIdleTimeout(notification *v1.IdleTimeoutNotification) {
+	select {
+	case sc.idleTimeoutQueue <- notification:
+	default:
+		log.Println("Idle timeout queue is full, dropping notification")
+	}
+}
+
+// processIdleTimeouts 按到达顺序逐个处理 idleTimeoutQueue 中的通知
+func (sc *Controller) processIdleTimeouts() {
+	for notification := range sc.idleTimeoutQueue {
+		sc.handleIdleTimeout(notification)
+	}
+}
+
+// handleIdleTimeout 将通知中的每个超时表项解码后发送到 IdleTimeoutChannel，
+// 在 IdleTimeoutDelete 策略下会先从交换机删除该表项。IdleTimeoutChannel 已满时丢弃表项，删除仍会执行。
+func (sc *Controller) handleIdleTimeout(notification *v1.IdleTimeoutNotification) {
+	for _, entry := range notification.TableEntry {
+		table, err := sc.tableByID(entry.TableId)
+		if err != nil {
+			log.Println("Unable to handle idle timeout notification:", err)
+			continue
+		}
+
+		tc := TableControl{batchWriter: batchWriter{control: sc}, table: table}
+		data, err := tc.decodeTableEntry(entry)
+		if err != nil {
+			log.Println("Unable to decode idle timeout entry:", err)
+			continue
+		}
+
+		idleTimeoutData := &IdleTimeoutData{
+			Table: table.Name,
+			Entry: data,
+		}
+		if sc.currentIdleTimeoutPolicy() == IdleTimeoutDelete {
+			if err := sc.Client.WriteUpdate(table.DeleteEntryByKey(entry)); err != nil {
+				log.Println("Unable to delete idle entry from table", table.Name, err)
+			} else {
+				idleTimeoutData.Deleted = true
+			}
+		}
+
+		select {
+		case sc.IdleTimeoutChannel <- idleTimeoutData:
+		default:
+			log.Println("Idle timeout channel is full, dropping entry from table", table.Name)
+		}
+	}
+}
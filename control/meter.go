@@ -0,0 +1,230 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/entity"
+)
+
+// MeterControl
+// 用于操作 P4 中的 meter 和 direct meter。主要功能包括按索引或按表项设置 meter 配置、读取配置，
+// 以及在目标设备支持时读取按颜色统计的计数。
+type MeterControl struct {
+	control *Controller
+	meter   *entity.Meter
+}
+
+func (config *MeterConfig) toP4() *v1.MeterConfig {
+	if config == nil {
+		return nil
+	}
+	return &v1.MeterConfig{
+		Cir:    config.CIR,
+		Cburst: config.CBurst,
+		Pir:    config.PIR,
+		Pburst: config.PBurst,
+		Eburst: config.EBurst,
+	}
+}
+
+func getMeterConfig(config *v1.MeterConfig) *MeterConfig {
+	if config == nil {
+		return nil
+	}
+	return &MeterConfig{
+		CIR:    config.Cir,
+		CBurst: config.Cburst,
+		PIR:    config.Pir,
+		PBurst: config.Pburst,
+		EBurst: config.Eburst,
+	}
+}
+
+func getMeterCounterData(data *v1.MeterCounterData) *MeterCounterData {
+	if data == nil {
+		return nil
+	}
+	return &MeterCounterData{
+		Green:  ColorCounterData{ByteCount: data.Green.GetByteCount(), PacketCount: data.Green.GetPacketCount()},
+		Yellow: ColorCounterData{ByteCount: data.Yellow.GetByteCount(), PacketCount: data.Yellow.GetPacketCount()},
+		Red:    ColorCounterData{ByteCount: data.Red.GetByteCount(), PacketCount: data.Red.GetPacketCount()},
+	}
+}
+
+// getMeterData 从 v1.Entity 中提取 meter 数据，支持 MeterEntry 和 DirectMeterEntry
+func (mc MeterControl) getMeterData(e *v1.Entity) (*MeterData, error) {
+	if entry := e.GetMeterEntry(); entry != nil {
+		return &MeterData{
+			Index:    entry.Index.GetIndex(),
+			Config:   getMeterConfig(entry.Config),
+			Counters: getMeterCounterData(entry.CounterData),
+		}, nil
+	}
+
+	entry := e.GetDirectMeterEntry()
+	if entry == nil {
+		return nil, errors.New("Entity is not a meter entry")
+	}
+	table, err := mc.table()
+	if err != nil {
+		return nil, err
+	}
+	matches, err := table.DecodeMatches(entry.TableEntry.GetMatch())
+	if err != nil {
+		return nil, err
+	}
+	return &MeterData{
+		Matches:  matches,
+		Config:   getMeterConfig(entry.Config),
+		Counters: getMeterCounterData(entry.CounterData),
+	}, nil
+}
+
+// table 返回 direct meter 所属的表
+func (mc MeterControl) table() (*entity.Table, error) {
+	if !mc.meter.IsDirect() {
+		return nil, fmt.Errorf("meter %s is not a direct meter", mc.meter.Name)
+	}
+	return mc.control.tableByID(mc.meter.TableID)
+}
+
+// entryKey 根据匹配字段和优先级构造 direct meter 所关联的表项
+func (mc MeterControl) entryKey(matches map[string]entity.Match, priority int32) (*v1.TableEntry, error) {
+	table, err := mc.table()
+	if err != nil {
+		return nil, err
+	}
+	return table.EntryKey(matches, priority)
+}
+
+func (mc MeterControl) checkIndexed() error {
+	if mc.meter.IsDirect() {
+		return fmt.Errorf("meter %s is a direct meter, use the table entry variants", mc.meter.Name)
+	}
+	return nil
+}
+
+// SetConfigAtIndex 设置 meter 在指定索引处的配置
+func (mc MeterControl) SetConfigAtIndex(index int64, config MeterConfig) error {
+	return mc.SetConfigAtIndexContext(context.Background(), index, config)
+}
+
+// SetConfigAtIndexContext 与 SetConfigAtIndex 相同，但使用 ctx 控制请求的截止时间和取消
+func (mc MeterControl) SetConfigAtIndexContext(ctx context.Context, index int64, config MeterConfig) error {
+	if err := mc.checkIndexed(); err != nil {
+		return err
+	}
+	return mc.control.Client.WriteUpdateContext(ctx, mc.meter.ConfigWithIndex(index, config.toP4()))
+}
+
+// ResetConfigAtIndex 将 meter 在指定索引处的配置恢复为默认值（所有报文标记为绿色）
+func (mc MeterControl) ResetConfigAtIndex(index int64) error {
+	return mc.ResetConfigAtIndexContext(context.Background(), index)
+}
+
+// ResetConfigAtIndexContext 与 ResetConfigAtIndex 相同，但使用 ctx 控制请求的截止时间和取消
+func (mc MeterControl) ResetConfigAtIndexContext(ctx context.Context, index int64) error {
+	if err := mc.checkIndexed(); err != nil {
+		return err
+	}
+	return mc.control.Client.WriteUpdateContext(ctx, mc.meter.ConfigWithIndex(index, nil))
+}
+
+// SetConfigOnEntry 设置与表项关联的 direct meter 配置
+func (mc MeterControl) SetConfigOnEntry(matches map[string]entity.Match, priority int32, config MeterConfig) error {
+	return mc.SetConfigOnEntryContext(context.Background(), matches, priority, config)
+}
+
+// SetConfigOnEntryContext 与 SetConfigOnEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (mc MeterControl) SetConfigOnEntryContext(ctx context.Context, matches map[string]entity.Match, priority int32, config MeterConfig) error {
+	tableEntry, err := mc.entryKey(matches, priority)
+	if err != nil {
+		return err
+	}
+	return mc.control.Client.WriteUpdateContext(ctx, mc.meter.ConfigForTableEntry(tableEntry, config.toP4()))
+}
+
+// ResetConfigOnEntry 将与表项关联的 direct meter 配置恢复为默认值
+func (mc MeterControl) ResetConfigOnEntry(matches map[string]entity.Match, priority int32) error {
+	return mc.ResetConfigOnEntryContext(context.Background(), matches, priority)
+}
+
+// ResetConfigOnEntryContext 与 ResetConfigOnEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (mc MeterControl) ResetConfigOnEntryContext(ctx context.Context, matches map[string]entity.Match, priority int32) error {
+	tableEntry, err := mc.entryKey(matches, priority)
+	if err != nil {
+		return err
+	}
+	return mc.control.Client.WriteUpdateContext(ctx, mc.meter.ConfigForTableEntry(tableEntry, nil))
+}
+
+// ReadValueAtIndex 读取 meter 在指定索引处的配置和计数
+func (mc MeterControl) ReadValueAtIndex(index int64) (*MeterData, error) {
+	return mc.ReadValueAtIndexContext(context.Background(), index)
+}
+
+// ReadValueAtIndexContext 与 ReadValueAtIndex 相同，但使用 ctx 控制请求的截止时间和取消
+func (mc MeterControl) ReadValueAtIndexContext(ctx context.Context, index int64) (*MeterData, error) {
+	if err := mc.checkIndexed(); err != nil {
+		return nil, err
+	}
+	return mc.readOne(ctx, mc.meter.ReadWithIndex(index))
+}
+
+// ReadValueOnEntry 读取与表项关联的 direct meter 配置和计数
+func (mc MeterControl) ReadValueOnEntry(matches map[string]entity.Match, priority int32) (*MeterData, error) {
+	return mc.ReadValueOnEntryContext(context.Background(), matches, priority)
+}
+
+// ReadValueOnEntryContext 与 ReadValueOnEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (mc MeterControl) ReadValueOnEntryContext(ctx context.Context, matches map[string]entity.Match, priority int32) (*MeterData, error) {
+	tableEntry, err := mc.entryKey(matches, priority)
+	if err != nil {
+		return nil, err
+	}
+	return mc.readOne(ctx, mc.meter.ReadForTableEntry(tableEntry))
+}
+
+// ReadValues 读取 meter 的所有配置；对于 direct meter 读取所属表中所有表项的配置
+func (mc MeterControl) ReadValues() ([]*MeterData, error) {
+	return mc.ReadValuesContext(context.Background())
+}
+
+// ReadValuesContext 与 ReadValues 相同，但使用 ctx 控制请求的截止时间和取消
+func (mc MeterControl) ReadValuesContext(ctx context.Context) ([]*MeterData, error) {
+	var e *v1.Entity
+	if mc.meter.IsDirect() {
+		e = mc.meter.ReadAllForTable()
+	} else {
+		e = mc.meter.Read()
+	}
+
+	res, err := mc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{e})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*MeterData, 0, len(res))
+	for _, item := range res {
+		meterData, err := mc.getMeterData(item)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, meterData)
+	}
+	return result, nil
+}
+
+func (mc MeterControl) readOne(ctx context.Context, e *v1.Entity) (*MeterData, error) {
+	res, err := mc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{e})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("No meter entries found")
+	}
+	return mc.getMeterData(res[0])
+}
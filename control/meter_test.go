@@ -0,0 +1,90 @@
+package control
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+	"p4r/entity"
+)
+
+func TestMeterConfigConversion(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *MeterConfig
+		want   *v1.MeterConfig
+	}{
+		{"two rate", &MeterConfig{CIR: 1000, CBurst: 100, PIR: 2000, PBurst: 200},
+			&v1.MeterConfig{Cir: 1000, Cburst: 100, Pir: 2000, Pburst: 200}},
+		{"single rate with excess burst", &MeterConfig{CIR: 1000, CBurst: 100, EBurst: 300},
+			&v1.MeterConfig{Cir: 1000, Cburst: 100, Eburst: 300}},
+		{"default config", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.config.toP4()
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("toP4() = %v, want %v", got, tt.want)
+			}
+			if back := getMeterConfig(got); !reflect.DeepEqual(back, tt.config) {
+				t.Errorf("getMeterConfig() = %+v, want %+v", back, tt.config)
+			}
+		})
+	}
+}
+
+func TestGetMeterData(t *testing.T) {
+	mc := MeterControl{meter: &entity.Meter{ID: 1, Name: "ingress.tenant_meter", Size: 128}}
+	counters := &v1.MeterCounterData{
+		Green: &v1.CounterData{ByteCount: 1500, PacketCount: 10},
+		Red:   &v1.CounterData{ByteCount: 64, PacketCount: 1},
+	}
+	tests := []struct {
+		name    string
+		entity  *v1.Entity
+		want    *MeterData
+		wantErr bool
+	}{
+		{
+			name: "config and counters",
+			entity: &v1.Entity{Entity: &v1.Entity_MeterEntry{MeterEntry: &v1.MeterEntry{
+				MeterId: 1, Index: &v1.Index{Index: 3}, Config: &v1.MeterConfig{Cir: 1000, Cburst: 100}, CounterData: counters,
+			}}},
+			want: &MeterData{
+				Index:  3,
+				Config: &MeterConfig{CIR: 1000, CBurst: 100},
+				Counters: &MeterCounterData{
+					Green: ColorCounterData{ByteCount: 1500, PacketCount: 10},
+					Red:   ColorCounterData{ByteCount: 64, PacketCount: 1},
+				},
+			},
+		},
+		{
+			name:   "default config without counters",
+			entity: &v1.Entity{Entity: &v1.Entity_MeterEntry{MeterEntry: &v1.MeterEntry{MeterId: 1, Index: &v1.Index{Index: 4}}}},
+			want:   &MeterData{Index: 4},
+		},
+		{
+			name:    "not a meter entry",
+			entity:  &v1.Entity{Entity: &v1.Entity_CounterEntry{CounterEntry: &v1.CounterEntry{CounterId: 1}}},
+			wantErr: true,
+		},
+		{
+			name:    "direct meter entry on an indexed meter",
+			entity:  &v1.Entity{Entity: &v1.Entity_DirectMeterEntry{DirectMeterEntry: &v1.DirectMeterEntry{}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mc.getMeterData(tt.entity)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMeterData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getMeterData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package control
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/entity"
+)
+
+// packetMetadata 返回 P4Info 中名为 name 的 controller_header（"packet_in" 或 "packet_out"）
+func (sc *Controller) packetMetadata(name string) (*entity.ControllerPacketMetadata, error) {
+	e, err := sc.Client.Index().Lookup("CONTROLLER_PACKET_METADATA", name)
+	if err != nil {
+		return nil, fmt.Errorf("P4 program does not declare a %s controller header: %w", name, err)
+	}
+	return e.(*entity.ControllerPacketMetadata), nil
+}
+
+// decodePacketIn 将 PacketIn 的元数据按 P4Info 中的名称解码。
+// 解码失败时仍然上送报文内容，只是不带元数据。
+func (sc *Controller) decodePacketIn(packet *v1.PacketIn) *PacketInData {
+	data := &PacketInData{
+		Payload: packet.Payload,
+	}
+
+	if len(packet.Metadata) == 0 {
+		return data
+	}
+	cpm, err := sc.packetMetadata("packet_in")
+	if err == nil {
+		data.Metadata, err = cpm.Decode(packet.Metadata)
+	}
+	if err != nil {
+		log.Println("Unable to decode PacketIn metadata:", err)
+	}
+	return data
+}
+
+// routePacketIn 将解码后的 PacketIn 发送到 PacketInChannel。
+// 通道已满时丢弃报文，避免阻塞消息路由，使仲裁和 digest 消息仍能被及时处理。
+func (sc *Controller) routePacketIn(packet *v1.PacketIn) {
+	select {
+	case sc.PacketInChannel <- sc.decodePacketIn(packet):
+	default:
+		log.Println("PacketIn channel is full, dropping packet")
+	}
+}
+
+// SendPacketOut 通过流通道向交换机发送一个 PacketOut 报文。
+// metadata 以 P4Info 中 packet_out 报头的字段名称为键。
+// 流断开时报文会在重连后发送；发送缓冲区已满或客户端已关闭时返回错误，不会阻塞。
+func (sc *Controller) SendPacketOut(payload []byte, metadata map[string][]byte) error {
+	var request *v1.StreamMessageRequest
+	if len(metadata) == 0 {
+		request = &v1.StreamMessageRequest{
+			Update: &v1.StreamMessageRequest_Packet{Packet: &v1.PacketOut{Payload: payload}},
+		}
+	} else {
+		cpm, err := sc.packetMetadata("packet_out")
+		if err != nil {
+			return err
+		}
+		request, err = cpm.PacketOut(payload, metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	return sc.Client.SendMessage(request)
+}
@@ -0,0 +1,152 @@
+package control
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"p4r/client"
+)
+
+// fakePipelineRuntime 接受任何 SetForwardingPipelineConfig 请求，用于让客户端加载 P4Info
+type fakePipelineRuntime struct {
+	v1.P4RuntimeClient
+}
+
+func (fakePipelineRuntime) SetForwardingPipelineConfig(context.Context, *v1.SetForwardingPipelineConfigRequest, ...grpc.CallOption) (*v1.SetForwardingPipelineConfigResponse, error) {
+	return &v1.SetForwardingPipelineConfigResponse{}, nil
+}
+
+// fakePacketClient 使用加载了 p4Info 的客户端，并记录发送的流消息
+type fakePacketClient struct {
+	client.P4RClient
+	sent []*v1.StreamMessageRequest
+}
+
+func (f *fakePacketClient) SendMessage(message *v1.StreamMessageRequest) error {
+	f.sent = append(f.sent, message)
+	return nil
+}
+
+func newPacketController(t *testing.T, p4Info *configv1.P4Info) (*Controller, *fakePacketClient) {
+	t.Helper()
+	c := &client.Client{P4RuntimeClient: fakePipelineRuntime{}}
+	if err := c.SetFwdPipeConfig(context.Background(), nil, p4Info); err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakePacketClient{P4RClient: c}
+	return &Controller{Client: fake}, fake
+}
+
+func packetP4Info() *configv1.P4Info {
+	return &configv1.P4Info{ControllerPacketMetadata: []*configv1.ControllerPacketMetadata{
+		{
+			Preamble: &configv1.Preamble{Id: 1, Name: "packet_in"},
+			Metadata: []*configv1.ControllerPacketMetadata_Metadata{{Id: 1, Name: "ingress_port", Bitwidth: 9}},
+		},
+		{
+			Preamble: &configv1.Preamble{Id: 2, Name: "packet_out"},
+			Metadata: []*configv1.ControllerPacketMetadata_Metadata{{Id: 1, Name: "egress_port", Bitwidth: 9}},
+		},
+	}}
+}
+
+func TestDecodePacketIn(t *testing.T) {
+	payload := []byte{0x01, 0x02}
+	tests := []struct {
+		name   string
+		p4Info *configv1.P4Info
+		packet *v1.PacketIn
+		want   *PacketInData
+	}{
+		{
+			name:   "metadata decoded by name",
+			p4Info: packetP4Info(),
+			packet: &v1.PacketIn{Payload: payload, Metadata: []*v1.PacketMetadata{{MetadataId: 1, Value: []byte{0x01, 0x00}}}},
+			want:   &PacketInData{Payload: payload, Metadata: map[string][]byte{"ingress_port": {0x01, 0x00}}},
+		},
+		{
+			name:   "no metadata",
+			p4Info: packetP4Info(),
+			packet: &v1.PacketIn{Payload: payload},
+			want:   &PacketInData{Payload: payload},
+		},
+		{
+			name:   "unknown metadata keeps the payload",
+			p4Info: packetP4Info(),
+			packet: &v1.PacketIn{Payload: payload, Metadata: []*v1.PacketMetadata{{MetadataId: 9, Value: []byte{0x01}}}},
+			want:   &PacketInData{Payload: payload},
+		},
+		{
+			name:   "program without packet_in header keeps the payload",
+			p4Info: &configv1.P4Info{},
+			packet: &v1.PacketIn{Payload: payload, Metadata: []*v1.PacketMetadata{{MetadataId: 1, Value: []byte{0x01}}}},
+			want:   &PacketInData{Payload: payload},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, _ := newPacketController(t, tt.p4Info)
+			if got := sc.decodePacketIn(tt.packet); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodePacketIn() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendPacketOut(t *testing.T) {
+	payload := []byte{0x01, 0x02}
+	tests := []struct {
+		name     string
+		p4Info   *configv1.P4Info
+		metadata map[string][]byte
+		want     *v1.PacketOut
+		wantErr  bool
+	}{
+		{
+			name:     "metadata encoded by name",
+			p4Info:   packetP4Info(),
+			metadata: map[string][]byte{"egress_port": {0x00, 0x03}},
+			want:     &v1.PacketOut{Payload: payload, Metadata: []*v1.PacketMetadata{{MetadataId: 1, Value: []byte{0x03}}}},
+		},
+		{
+			name:   "no metadata",
+			p4Info: &configv1.P4Info{},
+			want:   &v1.PacketOut{Payload: payload},
+		},
+		{
+			name:     "unknown metadata",
+			p4Info:   packetP4Info(),
+			metadata: map[string][]byte{"egress_port": {0x03}, "vlan": {0x01}},
+			wantErr:  true,
+		},
+		{
+			name:     "program without packet_out header",
+			p4Info:   &configv1.P4Info{},
+			metadata: map[string][]byte{"egress_port": {0x03}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, fake := newPacketController(t, tt.p4Info)
+			err := sc.SendPacketOut(payload, tt.metadata)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendPacketOut() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(fake.sent) != 0 {
+					t.Errorf("SendPacketOut() sent %v after an error", fake.sent)
+				}
+				return
+			}
+			if len(fake.sent) != 1 || !proto.Equal(fake.sent[0].GetPacket(), tt.want) {
+				t.Errorf("SendPacketOut() sent %v, want %v", fake.sent, tt.want)
+			}
+		})
+	}
+}
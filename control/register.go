@@ -0,0 +1,128 @@
+package control
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/entity"
+)
+
+// RegisterControl
+// 用于操作 P4 中的 register。主要功能包括读取指定索引的值、读取所有值、写入指定索引的值以及重置整个数组。
+// 值以 register type_spec 中的成员名称为键；元素为 bit<W> 等标量类型时使用 entity.ValueField。
+type RegisterControl struct {
+	control  *Controller
+	register *entity.Register
+}
+
+// getRegisterData 从 v1.Entity 中提取 register 数据，返回 RegisterData 结构体
+func (rc RegisterControl) getRegisterData(e *v1.Entity) (*RegisterData, error) {
+	registerEntry := e.GetRegisterEntry()
+	if registerEntry == nil {
+		return nil, errors.New("Entity is not a register entry")
+	}
+	values, err := rc.register.Decode(registerEntry.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &RegisterData{
+		Index:  registerEntry.Index.GetIndex(),
+		Values: values,
+	}, nil
+}
+
+// ReadValueAtIndex 读取 register 在指定索引处的值
+func (rc RegisterControl) ReadValueAtIndex(index int64) (*RegisterData, error) {
+	return rc.ReadValueAtIndexContext(context.Background(), index)
+}
+
+// ReadValueAtIndexContext 与 ReadValueAtIndex 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc RegisterControl) ReadValueAtIndexContext(ctx context.Context, index int64) (*RegisterData, error) {
+	entity := rc.register.ReadValueWithIndex(index)
+
+	res, err := rc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{entity})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("No register entries found at given index")
+	}
+	return rc.getRegisterData(res[0])
+}
+
+// StreamValues 异步读取 register 的所有值，并将结果通过通道发送出去。解码失败的值会被记录并跳过。
+// 读取中断时通道同样会被关闭，错误只记录日志，需要获取错误时使用 StreamValuesContext。
+func (rc RegisterControl) StreamValues() (chan *RegisterData, error) {
+	rdataChannel, errChannel, err := rc.StreamValuesContext(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	logStreamError(errChannel)
+	return rdataChannel, nil
+}
+
+// StreamValuesContext 与 StreamValues 相同，但使用 ctx 控制读取的截止时间和取消。
+// 数据通道关闭后，错误通道会收到读取结束的原因，正常结束时为 nil。
+func (rc RegisterControl) StreamValuesContext(ctx context.Context) (chan *RegisterData, <-chan error, error) {
+	entity := rc.register.ReadValue()
+
+	registerEntityCh, readErrCh, err := rc.control.Client.ReadEntitiesContext(ctx, []*v1.Entity{entity})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rdataChannel := make(chan *RegisterData, rc.register.Size)
+	errChannel := make(chan error, 1)
+	go func() {
+		err := func() error {
+			for e := range registerEntityCh {
+				registerData, err := rc.getRegisterData(e)
+				if err != nil {
+					log.Println("Unable to decode register entry:", err)
+					continue
+				}
+				select {
+				case rdataChannel <- registerData:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return <-readErrCh
+		}()
+		close(rdataChannel)
+		errChannel <- err
+		close(errChannel)
+	}()
+
+	return rdataChannel, errChannel, nil
+}
+
+// WriteValueAtIndex 将 values 写入 register 的指定索引处
+func (rc RegisterControl) WriteValueAtIndex(index int64, values map[string]interface{}) error {
+	return rc.WriteValueAtIndexContext(context.Background(), index, values)
+}
+
+// WriteValueAtIndexContext 与 WriteValueAtIndex 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc RegisterControl) WriteValueAtIndexContext(ctx context.Context, index int64, values map[string]interface{}) error {
+	update, err := rc.register.WriteValueWithIndex(index, values)
+	if err != nil {
+		return err
+	}
+	return rc.control.Client.WriteUpdateContext(ctx, update)
+}
+
+// Reset 将整个 register 数组恢复为初始值
+func (rc RegisterControl) Reset() error {
+	return rc.ResetContext(context.Background())
+}
+
+// ResetContext 与 Reset 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc RegisterControl) ResetContext(ctx context.Context) error {
+	update, err := rc.register.Reset()
+	if err != nil {
+		return err
+	}
+	return rc.control.Client.WriteUpdateContext(ctx, update)
+}
@@ -0,0 +1,175 @@
+package control
+
+import (
+	"context"
+	"errors"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
+	"p4r/entity"
+)
+
+// ReplicationControl 用于操作报文复制引擎（PRE），包括多播组和克隆会话的插入、修改、删除和读取。
+type ReplicationControl struct {
+	batchWriter
+}
+
+// WithBatch 返回一个写操作累积到 batch 中的 ReplicationControl，调用 batch.Send 时才会真正发送
+func (rc ReplicationControl) WithBatch(batch *client.Batch) ReplicationControl {
+	rc.batch = batch
+	return rc
+}
+
+// InsertMulticastGroup 插入多播组
+func (rc ReplicationControl) InsertMulticastGroup(group entity.MulticastGroup) error {
+	return rc.InsertMulticastGroupContext(context.Background(), group)
+}
+
+// InsertMulticastGroupContext 与 InsertMulticastGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) InsertMulticastGroupContext(ctx context.Context, group entity.MulticastGroup) error {
+	return rc.writeUpdate(ctx, group.Insert())
+}
+
+// ModifyMulticastGroup 替换多播组的副本列表
+func (rc ReplicationControl) ModifyMulticastGroup(group entity.MulticastGroup) error {
+	return rc.ModifyMulticastGroupContext(context.Background(), group)
+}
+
+// ModifyMulticastGroupContext 与 ModifyMulticastGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) ModifyMulticastGroupContext(ctx context.Context, group entity.MulticastGroup) error {
+	return rc.writeUpdate(ctx, group.Modify())
+}
+
+// DeleteMulticastGroup 删除多播组
+func (rc ReplicationControl) DeleteMulticastGroup(groupID uint32) error {
+	return rc.DeleteMulticastGroupContext(context.Background(), groupID)
+}
+
+// DeleteMulticastGroupContext 与 DeleteMulticastGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) DeleteMulticastGroupContext(ctx context.Context, groupID uint32) error {
+	group := entity.MulticastGroup{ID: groupID}
+	return rc.writeUpdate(ctx, group.Delete())
+}
+
+// ReadMulticastGroup 读取多播组
+func (rc ReplicationControl) ReadMulticastGroup(groupID uint32) (*entity.MulticastGroup, error) {
+	return rc.ReadMulticastGroupContext(context.Background(), groupID)
+}
+
+// ReadMulticastGroupContext 与 ReadMulticastGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) ReadMulticastGroupContext(ctx context.Context, groupID uint32) (*entity.MulticastGroup, error) {
+	groups, err := rc.readMulticastGroups(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, errors.New("No multicast group found")
+	}
+	return groups[0], nil
+}
+
+// ReadAllMulticastGroups 读取所有多播组
+func (rc ReplicationControl) ReadAllMulticastGroups() ([]*entity.MulticastGroup, error) {
+	return rc.ReadAllMulticastGroupsContext(context.Background())
+}
+
+// ReadAllMulticastGroupsContext 与 ReadAllMulticastGroups 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) ReadAllMulticastGroupsContext(ctx context.Context) ([]*entity.MulticastGroup, error) {
+	return rc.readMulticastGroups(ctx, 0)
+}
+
+func (rc ReplicationControl) readMulticastGroups(ctx context.Context, groupID uint32) ([]*entity.MulticastGroup, error) {
+	group := entity.MulticastGroup{ID: groupID}
+	res, err := rc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{group.Read()})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.MulticastGroup, 0, len(res))
+	for _, item := range res {
+		entry := item.GetPacketReplicationEngineEntry().GetMulticastGroupEntry()
+		if entry == nil {
+			return nil, errors.New("Entity is not a multicast group entry")
+		}
+		g := entity.DecodeMulticastGroup(entry)
+		result = append(result, &g)
+	}
+	return result, nil
+}
+
+// InsertCloneSession 插入克隆会话
+func (rc ReplicationControl) InsertCloneSession(session entity.CloneSession) error {
+	return rc.InsertCloneSessionContext(context.Background(), session)
+}
+
+// InsertCloneSessionContext 与 InsertCloneSession 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) InsertCloneSessionContext(ctx context.Context, session entity.CloneSession) error {
+	return rc.writeUpdate(ctx, session.Insert())
+}
+
+// ModifyCloneSession 修改克隆会话
+func (rc ReplicationControl) ModifyCloneSession(session entity.CloneSession) error {
+	return rc.ModifyCloneSessionContext(context.Background(), session)
+}
+
+// ModifyCloneSessionContext 与 ModifyCloneSession 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) ModifyCloneSessionContext(ctx context.Context, session entity.CloneSession) error {
+	return rc.writeUpdate(ctx, session.Modify())
+}
+
+// DeleteCloneSession 删除克隆会话
+func (rc ReplicationControl) DeleteCloneSession(sessionID uint32) error {
+	return rc.DeleteCloneSessionContext(context.Background(), sessionID)
+}
+
+// DeleteCloneSessionContext 与 DeleteCloneSession 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) DeleteCloneSessionContext(ctx context.Context, sessionID uint32) error {
+	session := entity.CloneSession{ID: sessionID}
+	return rc.writeUpdate(ctx, session.Delete())
+}
+
+// ReadCloneSession 读取克隆会话
+func (rc ReplicationControl) ReadCloneSession(sessionID uint32) (*entity.CloneSession, error) {
+	return rc.ReadCloneSessionContext(context.Background(), sessionID)
+}
+
+// ReadCloneSessionContext 与 ReadCloneSession 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) ReadCloneSessionContext(ctx context.Context, sessionID uint32) (*entity.CloneSession, error) {
+	sessions, err := rc.readCloneSessions(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, errors.New("No clone session found")
+	}
+	return sessions[0], nil
+}
+
+// ReadAllCloneSessions 读取所有克隆会话
+func (rc ReplicationControl) ReadAllCloneSessions() ([]*entity.CloneSession, error) {
+	return rc.ReadAllCloneSessionsContext(context.Background())
+}
+
+// ReadAllCloneSessionsContext 与 ReadAllCloneSessions 相同，但使用 ctx 控制请求的截止时间和取消
+func (rc ReplicationControl) ReadAllCloneSessionsContext(ctx context.Context) ([]*entity.CloneSession, error) {
+	return rc.readCloneSessions(ctx, 0)
+}
+
+func (rc ReplicationControl) readCloneSessions(ctx context.Context, sessionID uint32) ([]*entity.CloneSession, error) {
+	session := entity.CloneSession{ID: sessionID}
+	res, err := rc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{session.Read()})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*entity.CloneSession, 0, len(res))
+	for _, item := range res {
+		entry := item.GetPacketReplicationEngineEntry().GetCloneSessionEntry()
+		if entry == nil {
+			return nil, errors.New("Entity is not a clone session entry")
+		}
+		s := entity.DecodeCloneSession(entry)
+		result = append(result, &s)
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package control
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
+)
+
+// ErrOutsideRole 表示写请求涉及的 P4 实体不属于控制器的角色
+var ErrOutsideRole = errors.New("entity is outside the role of the control")
+
+// SetRole 修改控制器的角色并重新参与仲裁，仲裁结果通过 MastershipChannel 通知。
+// role 为 nil 时使用默认角色，即整个流水线。role.Entities 中有无法解析的名称时返回错误，不会重新仲裁。
+func (sc *Controller) SetRole(role *client.Role) error {
+	if err := sc.Client.SetRole(role); err != nil {
+		return err
+	}
+	return sc.PerformArbitration()
+}
+
+// checkRole 检查 updates 涉及的 P4 实体是否都属于控制器的角色。
+// 复制引擎、value set 等控制器没有建模的实体不做检查，由交换机根据角色配置决定。
+func (sc *Controller) checkRole(updates []*v1.Update) error {
+	allowed, err := sc.Client.RoleEntityIDs()
+	if err != nil || allowed == nil {
+		return err
+	}
+
+	for _, update := range updates {
+		id, ok := p4ObjectID(update.GetEntity())
+		if ok && !allowed[id] {
+			return fmt.Errorf("%w: entity ID %d is not in role %s", ErrOutsideRole, id, sc.Client.Role().Name)
+		}
+	}
+	return nil
+}
+
+// p4ObjectID 返回实体所属 P4 对象的 ID，direct counter 和 direct meter 返回所属表的 ID
+func p4ObjectID(e *v1.Entity) (uint32, bool) {
+	switch entity := e.GetEntity().(type) {
+	case *v1.Entity_TableEntry:
+		return entity.TableEntry.TableId, true
+	case *v1.Entity_CounterEntry:
+		return entity.CounterEntry.CounterId, true
+	case *v1.Entity_DirectCounterEntry:
+		return entity.DirectCounterEntry.GetTableEntry().GetTableId(), true
+	case *v1.Entity_MeterEntry:
+		return entity.MeterEntry.MeterId, true
+	case *v1.Entity_DirectMeterEntry:
+		return entity.DirectMeterEntry.GetTableEntry().GetTableId(), true
+	case *v1.Entity_RegisterEntry:
+		return entity.RegisterEntry.RegisterId, true
+	case *v1.Entity_ActionProfileMember:
+		return entity.ActionProfileMember.ActionProfileId, true
+	case *v1.Entity_ActionProfileGroup:
+		return entity.ActionProfileGroup.ActionProfileId, true
+	case *v1.Entity_DigestEntry:
+		return entity.DigestEntry.DigestId, true
+	default:
+		return 0, false
+	}
+}
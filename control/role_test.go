@@ -0,0 +1,87 @@
+package control
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
+)
+
+// fakeRoleClient 返回固定的角色和角色实体 ID
+type fakeRoleClient struct {
+	client.P4RClient
+	role *client.Role
+	ids  map[uint32]bool
+	err  error
+}
+
+func (f *fakeRoleClient) Role() *client.Role {
+	return f.role
+}
+
+func (f *fakeRoleClient) RoleEntityIDs() (map[uint32]bool, error) {
+	return f.ids, f.err
+}
+
+func tableUpdate(tableID uint32) *v1.Update {
+	return &v1.Update{Entity: &v1.Entity{Entity: &v1.Entity_TableEntry{TableEntry: &v1.TableEntry{TableId: tableID}}}}
+}
+
+func TestCheckRole(t *testing.T) {
+	role := &client.Role{Name: "r", Entities: []string{"ingress.acl"}}
+	resolveErr := errors.New("role r: unknown entity: ingress.typo")
+	multicast := &v1.Update{Entity: &v1.Entity{Entity: &v1.Entity_PacketReplicationEngineEntry{}}}
+
+	tests := []struct {
+		name    string
+		client  *fakeRoleClient
+		updates []*v1.Update
+		wantErr error
+	}{
+		{"default role", &fakeRoleClient{}, []*v1.Update{tableUpdate(9)}, nil},
+		{"entity in role", &fakeRoleClient{role: role, ids: map[uint32]bool{1: true}}, []*v1.Update{tableUpdate(1)}, nil},
+		{"entity outside role", &fakeRoleClient{role: role, ids: map[uint32]bool{1: true}}, []*v1.Update{tableUpdate(1), tableUpdate(2)}, ErrOutsideRole},
+		{"unmodelled entity is not checked", &fakeRoleClient{role: role, ids: map[uint32]bool{1: true}}, []*v1.Update{multicast}, nil},
+		{"unresolved role entity", &fakeRoleClient{role: role, err: resolveErr}, []*v1.Update{tableUpdate(1)}, resolveErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := &Controller{Client: tt.client}
+			if err := sc.checkRole(tt.updates); !errors.Is(err, tt.wantErr) {
+				t.Errorf("checkRole() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestP4ObjectID(t *testing.T) {
+	directKey := &v1.TableEntry{TableId: 3}
+
+	tests := []struct {
+		name   string
+		entity *v1.Entity
+		wantID uint32
+		wantOK bool
+	}{
+		{"table entry", &v1.Entity{Entity: &v1.Entity_TableEntry{TableEntry: &v1.TableEntry{TableId: 1}}}, 1, true},
+		{"counter entry", &v1.Entity{Entity: &v1.Entity_CounterEntry{CounterEntry: &v1.CounterEntry{CounterId: 2}}}, 2, true},
+		{"direct counter uses its table", &v1.Entity{Entity: &v1.Entity_DirectCounterEntry{DirectCounterEntry: &v1.DirectCounterEntry{TableEntry: directKey}}}, 3, true},
+		{"meter entry", &v1.Entity{Entity: &v1.Entity_MeterEntry{MeterEntry: &v1.MeterEntry{MeterId: 4}}}, 4, true},
+		{"direct meter uses its table", &v1.Entity{Entity: &v1.Entity_DirectMeterEntry{DirectMeterEntry: &v1.DirectMeterEntry{TableEntry: directKey}}}, 3, true},
+		{"register entry", &v1.Entity{Entity: &v1.Entity_RegisterEntry{RegisterEntry: &v1.RegisterEntry{RegisterId: 5}}}, 5, true},
+		{"action profile member", &v1.Entity{Entity: &v1.Entity_ActionProfileMember{ActionProfileMember: &v1.ActionProfileMember{ActionProfileId: 6}}}, 6, true},
+		{"action profile group", &v1.Entity{Entity: &v1.Entity_ActionProfileGroup{ActionProfileGroup: &v1.ActionProfileGroup{ActionProfileId: 7}}}, 7, true},
+		{"digest entry", &v1.Entity{Entity: &v1.Entity_DigestEntry{DigestEntry: &v1.DigestEntry{DigestId: 8}}}, 8, true},
+		{"replication entry", &v1.Entity{Entity: &v1.Entity_PacketReplicationEngineEntry{}}, 0, false},
+		{"nil entity", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, ok := p4ObjectID(tt.entity)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("p4ObjectID() = %d, %v, want %d, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
@@ -1,27 +1,356 @@
 package control
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
 	"p4r/entity"
 )
 
 type TableControl struct {
-	control *Controller
-	table   *entity.Table
+	batchWriter
+	table *entity.Table
+}
+
+// WithBatch 返回一个写操作累积到 batch 中的 TableControl，调用 batch.Send 时才会真正发送
+func (tc TableControl) WithBatch(batch *client.Batch) TableControl {
+	tc.batch = batch
+	return tc
+}
+
+// tableByID 根据 ID 查找表实体
+func (sc *Controller) tableByID(id uint32) (*entity.Table, error) {
+	e, err := sc.Client.Index().LookupID(id)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := e.(*entity.Table)
+	if !ok {
+		return nil, fmt.Errorf("ID %d is a %s, not a table", id, e.Type())
+	}
+	return t, nil
 }
 
 // InsertEntryRaw 直接插入表项的方法
-func (tc TableControl) InsertEntryRaw(action string, mf []entity.Match, params [][]byte) error {
-	actions := *tc.control.Client.GetEntities("ACTION")
-	actionID := actions[action].(*entity.Action).ID
+// opts 用于设置表项的可选属性，例如 entity.WithIdleTimeout。
+func (tc TableControl) InsertEntryRaw(action string, mf map[string]entity.Match, params map[string][]byte, opts ...entity.EntryOption) error {
+	return tc.InsertEntryRawContext(context.Background(), action, mf, params, opts...)
+}
+
+// InsertEntryRawContext 与 InsertEntryRaw 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) InsertEntryRawContext(ctx context.Context, action string, mf map[string]entity.Match, params map[string][]byte, opts ...entity.EntryOption) error {
+	return tc.InsertEntryWithPriorityContext(ctx, action, mf, params, 0, opts...)
+}
+
+// InsertEntryWithPriority 插入带优先级的表项，用于包含 ternary、range 或 optional 匹配的表。
+func (tc TableControl) InsertEntryWithPriority(action string, mf map[string]entity.Match, params map[string][]byte, priority int32, opts ...entity.EntryOption) error {
+	return tc.InsertEntryWithPriorityContext(context.Background(), action, mf, params, priority, opts...)
+}
+
+// InsertEntryWithPriorityContext 与 InsertEntryWithPriority 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) InsertEntryWithPriorityContext(ctx context.Context, action string, mf map[string]entity.Match, params map[string][]byte, priority int32, opts ...entity.EntryOption) error {
+	a, err := tc.control.action(action)
+	if err != nil {
+		return err
+	}
 
-	insertMessage := tc.table.InsertEntry(actionID, mf, params)
-	return tc.control.Client.WriteUpdate(insertMessage)
+	insertMessage, err := tc.table.InsertEntry(a, mf, params, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, insertMessage)
 }
 
 // InsertEntry 提供更简洁的表项插入接口
 func (tc TableControl) InsertEntry(action string, data map[string]interface{}) error {
+	return tc.InsertEntryContext(context.Background(), action, data)
+}
+
+// InsertEntryContext 与 InsertEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) InsertEntryContext(ctx context.Context, action string, data map[string]interface{}) error {
 	mf, params := tc.table.Transformer(data)
-	return tc.InsertEntryRaw(action, mf, params)
+	return tc.InsertEntryRawContext(ctx, action, mf, params)
+}
+
+// InsertEntryWithMember 插入一个引用 action profile member 的表项
+func (tc TableControl) InsertEntryWithMember(mf map[string]entity.Match, memberID uint32, priority int32, opts ...entity.EntryOption) error {
+	return tc.InsertEntryWithMemberContext(context.Background(), mf, memberID, priority, opts...)
+}
+
+// InsertEntryWithMemberContext 与 InsertEntryWithMember 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) InsertEntryWithMemberContext(ctx context.Context, mf map[string]entity.Match, memberID uint32, priority int32, opts ...entity.EntryOption) error {
+	insertMessage, err := tc.table.InsertEntryWithAction(entity.MemberTableAction(memberID), mf, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, insertMessage)
+}
+
+// InsertEntryWithGroup 插入一个引用 action selector group 的表项
+func (tc TableControl) InsertEntryWithGroup(mf map[string]entity.Match, groupID uint32, priority int32, opts ...entity.EntryOption) error {
+	return tc.InsertEntryWithGroupContext(context.Background(), mf, groupID, priority, opts...)
+}
+
+// InsertEntryWithGroupContext 与 InsertEntryWithGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) InsertEntryWithGroupContext(ctx context.Context, mf map[string]entity.Match, groupID uint32, priority int32, opts ...entity.EntryOption) error {
+	insertMessage, err := tc.table.InsertEntryWithAction(entity.GroupTableAction(groupID), mf, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, insertMessage)
+}
+
+// InsertEntryWithActionSet 插入一个 one-shot 表项，交换机会为 actions 隐式创建 member 和 group
+func (tc TableControl) InsertEntryWithActionSet(mf map[string]entity.Match, actions []WeightedAction, priority int32, opts ...entity.EntryOption) error {
+	return tc.InsertEntryWithActionSetContext(context.Background(), mf, actions, priority, opts...)
+}
+
+// InsertEntryWithActionSetContext 与 InsertEntryWithActionSet 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) InsertEntryWithActionSetContext(ctx context.Context, mf map[string]entity.Match, actions []WeightedAction, priority int32, opts ...entity.EntryOption) error {
+	tableAction, err := tc.control.actionSet(actions)
+	if err != nil {
+		return err
+	}
+	insertMessage, err := tc.table.InsertEntryWithAction(tableAction, mf, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, insertMessage)
+}
+
+// ModifyEntry 修改已存在表项的动作和参数
+func (tc TableControl) ModifyEntry(action string, mf map[string]entity.Match, params map[string][]byte, opts ...entity.EntryOption) error {
+	return tc.ModifyEntryContext(context.Background(), action, mf, params, opts...)
+}
+
+// ModifyEntryContext 与 ModifyEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ModifyEntryContext(ctx context.Context, action string, mf map[string]entity.Match, params map[string][]byte, opts ...entity.EntryOption) error {
+	return tc.ModifyEntryWithPriorityContext(ctx, action, mf, params, 0, opts...)
+}
+
+// ModifyEntryWithPriority 修改由匹配字段和优先级确定的表项
+func (tc TableControl) ModifyEntryWithPriority(action string, mf map[string]entity.Match, params map[string][]byte, priority int32, opts ...entity.EntryOption) error {
+	return tc.ModifyEntryWithPriorityContext(context.Background(), action, mf, params, priority, opts...)
+}
+
+// ModifyEntryWithPriorityContext 与 ModifyEntryWithPriority 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ModifyEntryWithPriorityContext(ctx context.Context, action string, mf map[string]entity.Match, params map[string][]byte, priority int32, opts ...entity.EntryOption) error {
+	a, err := tc.control.action(action)
+	if err != nil {
+		return err
+	}
+
+	modifyMessage, err := tc.table.ModifyEntry(a, mf, params, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, modifyMessage)
+}
+
+// ModifyEntryWithMember 将表项的动作修改为引用 action profile member
+func (tc TableControl) ModifyEntryWithMember(mf map[string]entity.Match, memberID uint32, priority int32, opts ...entity.EntryOption) error {
+	return tc.ModifyEntryWithMemberContext(context.Background(), mf, memberID, priority, opts...)
+}
+
+// ModifyEntryWithMemberContext 与 ModifyEntryWithMember 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ModifyEntryWithMemberContext(ctx context.Context, mf map[string]entity.Match, memberID uint32, priority int32, opts ...entity.EntryOption) error {
+	modifyMessage, err := tc.table.ModifyEntryWithAction(entity.MemberTableAction(memberID), mf, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, modifyMessage)
+}
+
+// ModifyEntryWithGroup 将表项的动作修改为引用 action selector group
+func (tc TableControl) ModifyEntryWithGroup(mf map[string]entity.Match, groupID uint32, priority int32, opts ...entity.EntryOption) error {
+	return tc.ModifyEntryWithGroupContext(context.Background(), mf, groupID, priority, opts...)
+}
+
+// ModifyEntryWithGroupContext 与 ModifyEntryWithGroup 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ModifyEntryWithGroupContext(ctx context.Context, mf map[string]entity.Match, groupID uint32, priority int32, opts ...entity.EntryOption) error {
+	modifyMessage, err := tc.table.ModifyEntryWithAction(entity.GroupTableAction(groupID), mf, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, modifyMessage)
+}
+
+// ModifyEntryWithActionSet 将 one-shot 表项的动作集替换为 actions
+func (tc TableControl) ModifyEntryWithActionSet(mf map[string]entity.Match, actions []WeightedAction, priority int32, opts ...entity.EntryOption) error {
+	return tc.ModifyEntryWithActionSetContext(context.Background(), mf, actions, priority, opts...)
+}
+
+// ModifyEntryWithActionSetContext 与 ModifyEntryWithActionSet 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ModifyEntryWithActionSetContext(ctx context.Context, mf map[string]entity.Match, actions []WeightedAction, priority int32, opts ...entity.EntryOption) error {
+	tableAction, err := tc.control.actionSet(actions)
+	if err != nil {
+		return err
+	}
+	modifyMessage, err := tc.table.ModifyEntryWithAction(tableAction, mf, priority, opts...)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, modifyMessage)
+}
+
+// DeleteEntry 删除表项
+func (tc TableControl) DeleteEntry(mf map[string]entity.Match) error {
+	return tc.DeleteEntryContext(context.Background(), mf)
+}
+
+// DeleteEntryContext 与 DeleteEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) DeleteEntryContext(ctx context.Context, mf map[string]entity.Match) error {
+	return tc.DeleteEntryWithPriorityContext(ctx, mf, 0)
+}
+
+// DeleteEntryWithPriority 删除由匹配字段和优先级确定的表项
+func (tc TableControl) DeleteEntryWithPriority(mf map[string]entity.Match, priority int32) error {
+	return tc.DeleteEntryWithPriorityContext(context.Background(), mf, priority)
+}
+
+// DeleteEntryWithPriorityContext 与 DeleteEntryWithPriority 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) DeleteEntryWithPriorityContext(ctx context.Context, mf map[string]entity.Match, priority int32) error {
+	deleteMessage, err := tc.table.DeleteEntry(mf, priority)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, deleteMessage)
+}
+
+// SetDefaultAction 设置表的默认动作
+func (tc TableControl) SetDefaultAction(action string, params map[string][]byte) error {
+	return tc.SetDefaultActionContext(context.Background(), action, params)
+}
+
+// SetDefaultActionContext 与 SetDefaultAction 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) SetDefaultActionContext(ctx context.Context, action string, params map[string][]byte) error {
+	a, err := tc.control.action(action)
+	if err != nil {
+		return err
+	}
+
+	modifyMessage, err := tc.table.SetDefaultAction(a, params)
+	if err != nil {
+		return err
+	}
+	return tc.writeUpdate(ctx, modifyMessage)
+}
+
+// ResetDefaultAction 将表的默认动作恢复为 P4 程序中声明的初始值
+func (tc TableControl) ResetDefaultAction() error {
+	return tc.ResetDefaultActionContext(context.Background())
+}
+
+// ResetDefaultActionContext 与 ResetDefaultAction 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ResetDefaultActionContext(ctx context.Context) error {
+	return tc.writeUpdate(ctx, tc.table.ResetDefaultAction())
+}
+
+// ReadEntry 读取与匹配字段对应的表项
+func (tc TableControl) ReadEntry(mf map[string]entity.Match) (*TableEntryData, error) {
+	return tc.ReadEntryContext(context.Background(), mf)
+}
+
+// ReadEntryContext 与 ReadEntry 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ReadEntryContext(ctx context.Context, mf map[string]entity.Match) (*TableEntryData, error) {
+	return tc.ReadEntryWithPriorityContext(ctx, mf, 0)
+}
+
+// ReadEntryWithPriority 读取由匹配字段和优先级确定的表项
+func (tc TableControl) ReadEntryWithPriority(mf map[string]entity.Match, priority int32) (*TableEntryData, error) {
+	return tc.ReadEntryWithPriorityContext(context.Background(), mf, priority)
+}
+
+// ReadEntryWithPriorityContext 与 ReadEntryWithPriority 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ReadEntryWithPriorityContext(ctx context.Context, mf map[string]entity.Match, priority int32) (*TableEntryData, error) {
+	entity, err := tc.table.ReadEntry(mf, priority)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{entity})
+	if err != nil {
+		return nil, err
+	}
+	if len(res) == 0 {
+		return nil, errors.New("No table entry found")
+	}
+	return tc.decodeTableEntry(res[0].GetTableEntry())
+}
+
+// ReadAllEntries 读取表中的所有表项并解码
+func (tc TableControl) ReadAllEntries() ([]*TableEntryData, error) {
+	return tc.ReadAllEntriesContext(context.Background())
+}
+
+// ReadAllEntriesContext 与 ReadAllEntries 相同，但使用 ctx 控制请求的截止时间和取消
+func (tc TableControl) ReadAllEntriesContext(ctx context.Context) ([]*TableEntryData, error) {
+	entity := tc.table.ReadAllEntries()
+
+	res, err := tc.control.Client.ReadEntitiesSyncContext(ctx, []*v1.Entity{entity})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*TableEntryData, 0, len(res))
+	for _, item := range res {
+		data, err := tc.decodeTableEntry(item.GetTableEntry())
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, data)
+	}
+	return result, nil
+}
+
+// decodeTableEntry 将 v1.TableEntry 解码为以名称为键的 TableEntryData
+func (tc TableControl) decodeTableEntry(entry *v1.TableEntry) (*TableEntryData, error) {
+	if entry == nil {
+		return nil, errors.New("Entity is not a table entry")
+	}
+
+	matches, err := tc.table.DecodeMatches(entry.Match)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &TableEntryData{
+		Matches:         matches,
+		Priority:        entry.Priority,
+		IsDefaultAction: entry.IsDefaultAction,
+		IdleTimeout:     time.Duration(entry.IdleTimeoutNs),
+	}
+
+	switch action := entry.GetAction().GetType().(type) {
+	case *v1.TableAction_Action:
+		data.Action, data.Params, err = tc.control.decodeAction(action.Action)
+		if err != nil {
+			return nil, err
+		}
+	case *v1.TableAction_ActionProfileMemberId:
+		data.MemberID = action.ActionProfileMemberId
+	case *v1.TableAction_ActionProfileGroupId:
+		data.GroupID = action.ActionProfileGroupId
+	case *v1.TableAction_ActionProfileActionSet:
+		for _, pa := range action.ActionProfileActionSet.ActionProfileActions {
+			name, params, err := tc.control.decodeAction(pa.Action)
+			if err != nil {
+				return nil, err
+			}
+			data.ActionSet = append(data.ActionSet, WeightedAction{
+				Action:    name,
+				Params:    params,
+				Weight:    pa.Weight,
+				WatchPort: pa.GetWatchPort(),
+			})
+		}
+	}
+
+	return data, nil
 }
 
 // RegisterTransformer 注册表项转换器
@@ -1,22 +1,54 @@
 package control
 
 import (
+	"time"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
 	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/client"
+	"p4r/entity"
+)
+
+// ErrUnknownEntity 和 ErrPipelineNotInstalled 是 ControlTable 访问器可能返回的错误，可以使用 errors.Is 判断
+var (
+	ErrUnknownEntity        = client.ErrUnknownEntity
+	ErrPipelineNotInstalled = client.ErrPipelineNotInstalled
 )
 
 type ControlTable interface {
-	Table(string) TableControl
-	Digest(string) DigestControl
-	Counter(string) CounterControl
+	Table(string) (TableControl, error)
+	Digest(string) (DigestControl, error)
+	Counter(string) (CounterControl, error)
+	Meter(string) (MeterControl, error)
+	Register(string) (RegisterControl, error)
+	ActionProfile(string) (ActionProfileControl, error)
+	Replication() ReplicationControl
+	MustTable(string) TableControl
+	MustDigest(string) DigestControl
+	MustCounter(string) CounterControl
+	MustMeter(string) MeterControl
+	MustRegister(string) RegisterControl
+	MustActionProfile(string) ActionProfileControl
 }
 
 type Control interface {
 	ControlTable
-	PerformArbitration()
+	PerformArbitration() error
 	IsMaster() bool
 	SetMastershipStatus(bool)
+	SetElectionID(*v1.Uint128)
+	YieldMastership() error
+	TakeOver() error
+	SetRole(*client.Role) error
 	Run()
-	InstallProgram(string, string) error
+	InstallProgram(string, string, ...client.FwdPipeOption) error
+	InstallProgramConfig([]byte, *configv1.P4Info, ...client.FwdPipeOption) error
+	CommitProgram() error
+	AttachProgram() error
+	EnsureProgram(string, string, ...client.FwdPipeOption) (bool, error)
+	EnsureProgramConfig([]byte, *configv1.P4Info, ...client.FwdPipeOption) (bool, error)
+	SendPacketOut([]byte, map[string][]byte) error
+	SetIdleTimeoutPolicy(IdleTimeoutPolicy)
 }
 
 type CounterData struct {
@@ -25,10 +57,145 @@ type CounterData struct {
 	Index       int64
 }
 
+// MeterConfig 是 meter 的速率和突发配置：
+//   - CIR/CBurst：承诺信息速率及其突发大小。
+//   - PIR/PBurst：峰值信息速率及其突发大小。
+//   - EBurst：超额突发大小，仅用于部分单速率三色 meter。
+//
+// 速率的单位由 meter 的计量单位决定（字节/秒或报文/秒）。
+type MeterConfig struct {
+	CIR    int64
+	CBurst int64
+	PIR    int64
+	PBurst int64
+	EBurst int64
+}
+
+// MeterData 是从交换机读回的 meter 数据：
+//   - Index：meter 索引，仅用于普通 meter。
+//   - Matches：关联表项的匹配条件，仅用于 direct meter。
+//   - Config：meter 配置，为 nil 时表示默认配置（所有报文标记为绿色）。
+//   - Counters：按颜色统计的计数，目标设备不支持时为 nil。
+type MeterData struct {
+	Index    int64
+	Matches  map[string]entity.Match
+	Config   *MeterConfig
+	Counters *MeterCounterData
+}
+
+// MeterCounterData 是 meter 按颜色统计的字节数和报文数
+type MeterCounterData struct {
+	Green  ColorCounterData
+	Yellow ColorCounterData
+	Red    ColorCounterData
+}
+
+type ColorCounterData struct {
+	ByteCount   int64
+	PacketCount int64
+}
+
+// RegisterData 是 register 在某个索引处的值，Values 以成员名称为键，值的类型见 entity.Register.Decode
+type RegisterData struct {
+	Index  int64
+	Values map[string]interface{}
+}
+
 type TableEntry v1.TableEntry
 
+// PacketInData 是交换机上送给控制器的报文：
+//   - Payload：报文内容。
+//   - Metadata：以 P4Info 中 packet_in 报头字段名称为键的元数据。
+type PacketInData struct {
+	Payload  []byte
+	Metadata map[string][]byte
+}
+
+// TableEntryData 是从交换机读回并解码后的表项：
+//   - Matches：以字段名称为键的匹配条件。
+//   - Action：动作名称，仅用于直接动作。
+//   - Params：以参数名称为键的动作参数值，仅用于直接动作。
+//   - MemberID：引用的 action profile member，仅用于通过 action profile 实现的表。
+//   - GroupID：引用的 action selector group，仅用于通过 action selector 实现的表。
+//   - ActionSet：one-shot 表项的动作集。
+//   - Priority：表项优先级。
+//   - IsDefaultAction：是否为默认表项。
+//   - IdleTimeout：表项的空闲超时时间，0 表示未设置。
+type TableEntryData struct {
+	Matches         map[string]entity.Match
+	Action          string
+	Params          map[string][]byte
+	MemberID        uint32
+	GroupID         uint32
+	ActionSet       []WeightedAction
+	Priority        int32
+	IsDefaultAction bool
+	IdleTimeout     time.Duration
+}
+
+// MastershipData 描述一次主控权变化：
+//   - IsMaster：控制器当前是否为主控制器。
+//   - PrimaryElectionID：交换机在仲裁结果中报告的当前主控制器的选举 ID，没有主控制器或状态由本地设置时为 nil。
+//   - Message：交换机在仲裁结果中附带的状态描述。
+type MastershipData struct {
+	IsMaster          bool
+	PrimaryElectionID *v1.Uint128
+	Message           string
+}
+
+// IdleTimeoutPolicy 决定控制器收到 IdleTimeoutNotification 后如何处理超时的表项
+type IdleTimeoutPolicy int
+
+const (
+	// IdleTimeoutNotify 只将超时的表项发送到 IdleTimeoutChannel
+	IdleTimeoutNotify IdleTimeoutPolicy = iota
+	// IdleTimeoutDelete 先从交换机删除超时的表项，再发送到 IdleTimeoutChannel，例如用于 MAC 老化
+	IdleTimeoutDelete
+)
+
+// IdleTimeoutData 是一条空闲超时的表项：
+//   - Table：表名称。
+//   - Entry：解码后的表项。
+//   - Deleted：在 IdleTimeoutDelete 策略下表项是否已被成功删除。
+type IdleTimeoutData struct {
+	Table   string
+	Entry   *TableEntryData
+	Deleted bool
+}
+
+// WeightedAction 是 one-shot 表项动作集中的一个动作：
+//   - Action：动作名称。
+//   - Params：以参数名称为键的动作参数值。
+//   - Weight：权重，0 按 1 处理。
+//   - WatchPort：监视端口，为 nil 时不监视。
+type WeightedAction struct {
+	Action    string
+	Params    map[string][]byte
+	Weight    int32
+	WatchPort []byte
+}
+
+// ActionProfileMemberData 是从交换机读回的 action profile member
+type ActionProfileMemberData struct {
+	MemberID uint32
+	Action   string
+	Params   map[string][]byte
+}
+
+// ActionProfileGroupData 是从交换机读回的 action selector group
+type ActionProfileGroupData struct {
+	GroupID uint32
+	Members []entity.GroupMember
+	MaxSize int32
+}
+
+// DirectCounterData 是从交换机读回的 direct counter 数据：
+//   - TableEntry：关联的表项。
+//   - Matches：按 P4Info 解码后的表项匹配条件，以字段名称为键。
+//   - ByteCount/PacketCount：计数值。
 type DirectCounterData struct {
 	TableEntry  *TableEntry
+	Matches     map[string]entity.Match
 	ByteCount   int64
 	PacketCount int64
 }
@@ -0,0 +1,253 @@
+package entity
+
+import (
+	"fmt"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// ActionProfile 保存 P4Info 中一个 action profile（或 action selector）的信息：
+//   - ID：action profile 的唯一标识符。
+//   - Name：action profile 的名称。
+//   - TableIDs：使用该 action profile 实现的表。
+//   - WithSelector：是否为 action selector，只有 action selector 支持 group。
+//   - Size：可容纳的 member 数量上限。
+//   - MaxGroupSize：单个 group 中 member 数量的上限，0 表示不限制。
+type ActionProfile struct {
+	ID           uint32
+	Name         string
+	TableIDs     []uint32
+	WithSelector bool
+	Size         int64
+	MaxGroupSize int32
+}
+
+// GroupMember 是 group 中的一个 member：
+//   - MemberID：member 的 ID。
+//   - Weight：权重，用于 WCMP，0 按 1 处理。
+//   - WatchPort：监视端口，端口失效时该 member 不参与选择；为 nil 时不监视。
+type GroupMember struct {
+	MemberID  uint32
+	Weight    int32
+	WatchPort []byte
+}
+
+func actionProfileMemberUpdate(updateType v1.Update_Type, member *v1.ActionProfileMember) *v1.Update {
+	return &v1.Update{
+		Type: updateType,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_ActionProfileMember{ActionProfileMember: member},
+		},
+	}
+}
+
+func actionProfileGroupUpdate(updateType v1.Update_Type, group *v1.ActionProfileGroup) *v1.Update {
+	return &v1.Update{
+		Type: updateType,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_ActionProfileGroup{ActionProfileGroup: group},
+		},
+	}
+}
+
+func (ap *ActionProfile) buildMember(memberID uint32, action *Action, params map[string][]byte) (*v1.ActionProfileMember, error) {
+	actionParams, err := action.BuildParams(params)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.ActionProfileMember{
+		ActionProfileId: ap.ID,
+		MemberId:        memberID,
+		Action: &v1.Action{
+			ActionId: action.ID,
+			Params:   actionParams,
+		},
+	}, nil
+}
+
+// InsertMember 插入一个 member，member 绑定一个动作及其参数
+func (ap *ActionProfile) InsertMember(memberID uint32, action *Action, params map[string][]byte) (*v1.Update, error) {
+	member, err := ap.buildMember(memberID, action, params)
+	if err != nil {
+		return nil, err
+	}
+	return actionProfileMemberUpdate(v1.Update_INSERT, member), nil
+}
+
+// ModifyMember 修改一个 member 的动作和参数
+func (ap *ActionProfile) ModifyMember(memberID uint32, action *Action, params map[string][]byte) (*v1.Update, error) {
+	member, err := ap.buildMember(memberID, action, params)
+	if err != nil {
+		return nil, err
+	}
+	return actionProfileMemberUpdate(v1.Update_MODIFY, member), nil
+}
+
+// DeleteMember 删除一个 member
+func (ap *ActionProfile) DeleteMember(memberID uint32) *v1.Update {
+	member := &v1.ActionProfileMember{
+		ActionProfileId: ap.ID,
+		MemberId:        memberID,
+	}
+	return actionProfileMemberUpdate(v1.Update_DELETE, member)
+}
+
+// ReadMember 读取一个 member，memberID 为 0 时读取所有 member
+func (ap *ActionProfile) ReadMember(memberID uint32) *v1.Entity {
+	member := &v1.ActionProfileMember{
+		ActionProfileId: ap.ID,
+		MemberId:        memberID,
+	}
+	return &v1.Entity{
+		Entity: &v1.Entity_ActionProfileMember{ActionProfileMember: member},
+	}
+}
+
+func (ap *ActionProfile) buildGroup(groupID uint32, members []GroupMember, maxSize int32) (*v1.ActionProfileGroup, error) {
+	if !ap.WithSelector {
+		return nil, fmt.Errorf("action profile %s is not an action selector and does not support groups", ap.Name)
+	}
+	if ap.MaxGroupSize > 0 && maxSize > ap.MaxGroupSize {
+		return nil, fmt.Errorf("action profile %s: group max size %d exceeds %d", ap.Name, maxSize, ap.MaxGroupSize)
+	}
+	if maxSize > 0 && int32(len(members)) > maxSize {
+		return nil, fmt.Errorf("action profile %s: group %d has %d members, max size is %d", ap.Name, groupID, len(members), maxSize)
+	}
+
+	group := &v1.ActionProfileGroup{
+		ActionProfileId: ap.ID,
+		GroupId:         groupID,
+		MaxSize:         maxSize,
+	}
+	for _, m := range members {
+		member := &v1.ActionProfileGroup_Member{
+			MemberId: m.MemberID,
+			Weight:   weight(m.Weight),
+		}
+		if m.WatchPort != nil {
+			member.WatchKind = &v1.ActionProfileGroup_Member_WatchPort{WatchPort: m.WatchPort}
+		}
+		group.Members = append(group.Members, member)
+	}
+	return group, nil
+}
+
+// InsertGroup 插入一个 group，maxSize 为 group 将来可以容纳的 member 数量上限，0 表示不指定
+func (ap *ActionProfile) InsertGroup(groupID uint32, members []GroupMember, maxSize int32) (*v1.Update, error) {
+	group, err := ap.buildGroup(groupID, members, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return actionProfileGroupUpdate(v1.Update_INSERT, group), nil
+}
+
+// ModifyGroup 修改一个 group 的 member 列表
+func (ap *ActionProfile) ModifyGroup(groupID uint32, members []GroupMember, maxSize int32) (*v1.Update, error) {
+	group, err := ap.buildGroup(groupID, members, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	return actionProfileGroupUpdate(v1.Update_MODIFY, group), nil
+}
+
+// DeleteGroup 删除一个 group
+func (ap *ActionProfile) DeleteGroup(groupID uint32) *v1.Update {
+	group := &v1.ActionProfileGroup{
+		ActionProfileId: ap.ID,
+		GroupId:         groupID,
+	}
+	return actionProfileGroupUpdate(v1.Update_DELETE, group)
+}
+
+// ReadGroup 读取一个 group，groupID 为 0 时读取所有 group
+func (ap *ActionProfile) ReadGroup(groupID uint32) *v1.Entity {
+	group := &v1.ActionProfileGroup{
+		ActionProfileId: ap.ID,
+		GroupId:         groupID,
+	}
+	return &v1.Entity{
+		Entity: &v1.Entity_ActionProfileGroup{ActionProfileGroup: group},
+	}
+}
+
+// DecodeGroupMembers 将交换机返回的 group member 还原为 GroupMember
+func DecodeGroupMembers(members []*v1.ActionProfileGroup_Member) []GroupMember {
+	result := make([]GroupMember, 0, len(members))
+	for _, m := range members {
+		result = append(result, GroupMember{
+			MemberID:  m.MemberId,
+			Weight:    m.Weight,
+			WatchPort: m.GetWatchPort(),
+		})
+	}
+	return result
+}
+
+func weight(w int32) int32 {
+	if w <= 0 {
+		return 1
+	}
+	return w
+}
+
+// MemberTableAction 返回引用 action profile member 的 TableAction
+func MemberTableAction(memberID uint32) *v1.TableAction {
+	return &v1.TableAction{
+		Type: &v1.TableAction_ActionProfileMemberId{ActionProfileMemberId: memberID},
+	}
+}
+
+// GroupTableAction 返回引用 action selector group 的 TableAction
+func GroupTableAction(groupID uint32) *v1.TableAction {
+	return &v1.TableAction{
+		Type: &v1.TableAction_ActionProfileGroupId{ActionProfileGroupId: groupID},
+	}
+}
+
+// NewActionProfileAction 返回 one-shot 表项动作集中的一个动作：权重 w 为 0 时按 1 处理，watchPort 为 nil 时不监视端口
+func NewActionProfileAction(action *Action, params map[string][]byte, w int32, watchPort []byte) (*v1.ActionProfileAction, error) {
+	actionParams, err := action.BuildParams(params)
+	if err != nil {
+		return nil, err
+	}
+	profileAction := &v1.ActionProfileAction{
+		Action: &v1.Action{
+			ActionId: action.ID,
+			Params:   actionParams,
+		},
+		Weight: weight(w),
+	}
+	if watchPort != nil {
+		profileAction.WatchKind = &v1.ActionProfileAction_WatchPort{WatchPort: watchPort}
+	}
+	return profileAction, nil
+}
+
+// ActionSetTableAction 返回 one-shot 表项使用的 TableAction，由交换机隐式创建 member 和 group
+func ActionSetTableAction(actions []*v1.ActionProfileAction) *v1.TableAction {
+	return &v1.TableAction{
+		Type: &v1.TableAction_ActionProfileActionSet{
+			ActionProfileActionSet: &v1.ActionProfileActionSet{ActionProfileActions: actions},
+		},
+	}
+}
+
+func (ap *ActionProfile) Type() string {
+	return "ACTION_PROFILE"
+}
+
+func (ap *ActionProfile) GetID() uint32 {
+	return ap.ID
+}
+
+func GetActionProfile(ap *configv1.ActionProfile) ActionProfile {
+	return ActionProfile{
+		ID:           ap.Preamble.Id,
+		Name:         ap.Preamble.Name,
+		TableIDs:     ap.TableIds,
+		WithSelector: ap.WithSelector,
+		Size:         ap.Size,
+		MaxGroupSize: ap.MaxGroupSize,
+	}
+}
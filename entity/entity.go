@@ -1,8 +1,14 @@
 package entity
 
 import (
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
 	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
 	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/utils"
 )
 
 // Id：这是一个 32 位无符号整数，用于唯一标识 P4 对象。所有 P4 对象的 ID 共享同一个编号空间，这意味着表的 ID 不能与计数器的 ID 重叠。
@@ -12,8 +18,16 @@ import (
 // configv1.Action 描述 P4 运行时中的动作（Action）对象
 
 type Action struct {
-	Name string
-	ID   uint32
+	Name   string
+	ID     uint32
+	Params []Param
+}
+
+// Param 保存 P4Info 中动作参数的元数据：参数 ID、名称和位宽。
+type Param struct {
+	ID       uint32
+	Name     string
+	Bitwidth int32
 }
 
 func (a *Action) GetID() uint32 {
@@ -23,10 +37,77 @@ func (a *Action) Type() string {
 	return "ACTION"
 }
 
+// BuildParams 根据参数名称将 params 转换为 P4Runtime 的 Action_Param，参数 ID 取自 P4Info。
+// 动作的每个参数都必须提供，且不允许出现 P4Info 中未声明的参数。
+func (a *Action) BuildParams(params map[string][]byte) ([]*v1.Action_Param, error) {
+	for name := range params {
+		if a.param(name) == nil {
+			return nil, fmt.Errorf("action %s has no parameter named %s", a.Name, name)
+		}
+	}
+
+	result := make([]*v1.Action_Param, 0, len(a.Params))
+	for _, p := range a.Params {
+		value, ok := params[p.Name]
+		if !ok {
+			return nil, fmt.Errorf("action %s: missing parameter %s", a.Name, p.Name)
+		}
+		canonical, err := utils.Canonicalize(value, p.Bitwidth)
+		if err != nil {
+			return nil, fmt.Errorf("action %s: parameter %s: %v", a.Name, p.Name, err)
+		}
+		result = append(result, &v1.Action_Param{
+			ParamId: p.ID,
+			Value:   canonical,
+		})
+	}
+	return result, nil
+}
+
+func (a *Action) param(name string) *Param {
+	for i := range a.Params {
+		if a.Params[i].Name == name {
+			return &a.Params[i]
+		}
+	}
+	return nil
+}
+
+// DecodeParams 将交换机返回的 Action_Param 还原为以参数名称为键的值。
+func (a *Action) DecodeParams(params []*v1.Action_Param) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(params))
+	for _, p := range params {
+		param := a.paramByID(p.ParamId)
+		if param == nil {
+			return nil, fmt.Errorf("action %s has no parameter with ID %d", a.Name, p.ParamId)
+		}
+		result[param.Name] = p.Value
+	}
+	return result, nil
+}
+
+func (a *Action) paramByID(id uint32) *Param {
+	for i := range a.Params {
+		if a.Params[i].ID == id {
+			return &a.Params[i]
+		}
+	}
+	return nil
+}
+
 func GetAction(ac *configv1.Action) Action {
+	params := make([]Param, 0, len(ac.Params))
+	for _, p := range ac.Params {
+		params = append(params, Param{
+			ID:       p.Id,
+			Name:     p.Name,
+			Bitwidth: p.Bitwidth,
+		})
+	}
 	return Action{
-		Name: ac.Preamble.Name,
-		ID:   ac.Preamble.Id,
+		Name:   ac.Preamble.Name,
+		ID:     ac.Preamble.Id,
+		Params: params,
 	}
 }
 
@@ -93,8 +174,37 @@ func GetCounter(counter *configv1.Counter) Counter {
 	}
 }
 
+// Digest 保存 digest 的 ID、名称以及由 P4Info type_spec 展开的成员列表。
 type Digest struct {
-	ID uint32
+	ID     uint32
+	Name   string
+	Fields []DataField
+}
+
+// Decode 将 DigestList 中的一个 P4Data 解码为以成员名称为键的值，值的类型由成员的 type_spec 决定：
+// bool 成员为 bool，位宽不超过 64 的 bit<W>/int<W> 成员为 uint64/int64，更宽的成员为 *big.Int，
+// 嵌套的 struct、tuple 和 header 成员为 map[string]interface{}。
+func (d *Digest) Decode(data *v1.P4Data) (map[string]interface{}, error) {
+	if d.Fields == nil {
+		return nil, fmt.Errorf("digest %s has an unsupported type_spec", d.Name)
+	}
+	values, err := decodeP4Data(d.Fields, data)
+	if err != nil {
+		return nil, fmt.Errorf("digest %s: %v", d.Name, err)
+	}
+	return values, nil
+}
+
+// DecodeInto 将 DigestList 中的一个 P4Data 解码到 out 指向的结构体中，
+// 结构体字段通过 `p4:"member_name"` 标签与 digest 成员对应，string 字段可以用 `p4:"member_name,ipv4"` 等形式指定格式。
+func (d *Digest) DecodeInto(data *v1.P4Data, out interface{}) error {
+	if d.Fields == nil {
+		return fmt.Errorf("digest %s has an unsupported type_spec", d.Name)
+	}
+	if err := decodeInto(d.Fields, data, out); err != nil {
+		return fmt.Errorf("digest %s: %v", d.Name, err)
+	}
+	return nil
 }
 
 // Insert 插入一条digest条目
@@ -154,9 +264,11 @@ func (d *Digest) GetID() uint32 {
 	return d.ID
 }
 
-func GetDigest(digest *configv1.Digest) Digest {
+func GetDigest(digest *configv1.Digest, typeInfo *configv1.P4TypeInfo) Digest {
 	return Digest{
-		ID: digest.Preamble.Id,
+		ID:     digest.Preamble.Id,
+		Name:   digest.Preamble.Name,
+		Fields: getDataFields(digest.TypeSpec, typeInfo),
 	}
 }
 
@@ -164,20 +276,163 @@ func GetDigest(digest *configv1.Digest) Digest {
 //
 //	ID：表的唯一标识符（uint32 类型）。
 //	Name：表的名称（string 类型）。
+//	MatchFields：P4Info 中声明的匹配字段元数据。
+//	IdleTimeout：表是否支持空闲超时通知（idle_timeout_behavior 为 NOTIFY_CONTROL）。
+//	ImplementationID：实现该表的 action profile 或 action selector 的 ID，直接表为 0。
 //	Transformer：类型为 TableEntryTransformer 的函数，用于将数据转换为与 P4 Runtime 兼容的格式。
 type Table struct {
-	ID          uint32
-	Name        string
-	Transformer TableEntryTransformer
+	ID               uint32
+	Name             string
+	MatchFields      []MatchField
+	IdleTimeout      bool
+	ImplementationID uint32
+	Transformer      TableEntryTransformer
+}
+
+// EntryOption 用于设置表项的可选属性，例如空闲超时。
+type EntryOption func(entry *v1.TableEntry)
+
+// WithIdleTimeout 设置表项的空闲超时时间，表项在 timeout 内没有命中时交换机会发送 IdleTimeoutNotification。
+// 只有 idle_timeout_behavior 为 NOTIFY_CONTROL 的表支持该选项。
+func WithIdleTimeout(timeout time.Duration) EntryOption {
+	return func(entry *v1.TableEntry) {
+		entry.IdleTimeoutNs = timeout.Nanoseconds()
+	}
+}
+
+// MatchField 保存 P4Info 中表匹配字段的元数据：字段 ID、名称、位宽和匹配类型。
+type MatchField struct {
+	ID        uint32
+	Name      string
+	Bitwidth  int32
+	MatchType configv1.MatchField_MatchType
+}
+
+// BuildMatches 根据字段名称将 mfs 转换为 P4Runtime 的 FieldMatch，字段 ID 取自 P4Info。
+// 不允许出现未声明的字段或与声明不一致的匹配类型；EXACT 类型的字段不可省略。
+func (t *Table) BuildMatches(mfs map[string]Match) ([]*v1.FieldMatch, error) {
+	for name, m := range mfs {
+		field := t.matchField(name)
+		if field == nil {
+			return nil, fmt.Errorf("table %s has no match field named %s", t.Name, name)
+		}
+		if m.matchType() != field.MatchType {
+			return nil, fmt.Errorf("table %s: match field %s is %s, got %s match",
+				t.Name, name, field.MatchType, m.matchType())
+		}
+	}
+
+	result := make([]*v1.FieldMatch, 0, len(mfs))
+	for _, field := range t.MatchFields {
+		m, ok := mfs[field.Name]
+		if !ok {
+			if field.MatchType == configv1.MatchField_EXACT {
+				return nil, fmt.Errorf("table %s: missing exact match field %s", t.Name, field.Name)
+			}
+			continue
+		}
+		if err := checkValueLength(m, field.Bitwidth); err != nil {
+			return nil, fmt.Errorf("table %s: match field %s: %v", t.Name, field.Name, err)
+		}
+		// 通配的匹配字段按规范不出现在表项中。
+		fm, err := canonicalFieldMatch(m.get(field.ID), field.Bitwidth)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: match field %s: %v", t.Name, field.Name, err)
+		}
+		if fm != nil {
+			result = append(result, fm)
+		}
+	}
+	return result, nil
+}
+
+// canonicalFieldMatch 按字段位宽将 FieldMatch 中的值转换为规范形式，并检查是否溢出。
+// 对于通配的匹配（掩码全 0 的 ternary、前缀长度为 0 的 lpm、覆盖整个取值范围的 range），返回 nil。
+func canonicalFieldMatch(fm *v1.FieldMatch, bitwidth int32) (*v1.FieldMatch, error) {
+	if fm == nil {
+		return nil, nil
+	}
+	if bitwidth <= 0 {
+		return nil, fmt.Errorf("invalid bitwidth %d", bitwidth)
+	}
+
+	var err error
+	switch m := fm.FieldMatchType.(type) {
+	case *v1.FieldMatch_Exact_:
+		m.Exact.Value, err = utils.Canonicalize(m.Exact.Value, bitwidth)
+	case *v1.FieldMatch_Lpm:
+		if m.Lpm.PrefixLen < 0 || m.Lpm.PrefixLen > bitwidth {
+			return nil, fmt.Errorf("invalid prefix length %d for %d-bit field", m.Lpm.PrefixLen, bitwidth)
+		}
+		if m.Lpm.PrefixLen == 0 {
+			return nil, nil
+		}
+		m.Lpm.Value, err = maskPrefix(m.Lpm.Value, m.Lpm.PrefixLen, bitwidth)
+	case *v1.FieldMatch_Ternary_:
+		if m.Ternary.Mask, err = utils.Canonicalize(m.Ternary.Mask, bitwidth); err != nil {
+			return nil, err
+		}
+		m.Ternary.Value, err = utils.Canonicalize(m.Ternary.Value, bitwidth)
+	case *v1.FieldMatch_Range_:
+		var low, high *big.Int
+		if low, err = utils.DecodeBigInt(m.Range.Low, bitwidth); err != nil {
+			return nil, err
+		}
+		if high, err = utils.DecodeBigInt(m.Range.High, bitwidth); err != nil {
+			return nil, err
+		}
+		if low.Cmp(high) > 0 {
+			return nil, fmt.Errorf("range low 0x%x is greater than high 0x%x", m.Range.Low, m.Range.High)
+		}
+		if low.Sign() == 0 && high.Cmp(maxValue(bitwidth)) == 0 {
+			return nil, nil
+		}
+		m.Range.Low, _ = utils.EncodeBigInt(low, bitwidth)
+		m.Range.High, _ = utils.EncodeBigInt(high, bitwidth)
+	case *v1.FieldMatch_Optional_:
+		m.Optional.Value, err = utils.Canonicalize(m.Optional.Value, bitwidth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fm, nil
+}
+
+// maskPrefix 将 value 转换为规范形式，并清除前 prefixLen 位之后的所有位。
+// 前缀从字段的最高位（第 bitwidth-1 位）开始计算，与 value 的字节对齐方式无关。
+func maskPrefix(value []byte, prefixLen, bitwidth int32) ([]byte, error) {
+	v, err := utils.DecodeBigInt(value, bitwidth)
+	if err != nil {
+		return nil, err
+	}
+	hostBits := uint(bitwidth - prefixLen)
+	mask := new(big.Int).Lsh(maxValue(prefixLen), hostBits)
+	return utils.EncodeBigInt(v.And(v, mask), bitwidth)
+}
+
+// maxValue 返回 bitwidth 位无符号整数的最大值
+func maxValue(bitwidth int32) *big.Int {
+	return new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(bitwidth)), big.NewInt(1))
+}
+
+func (t *Table) matchField(name string) *MatchField {
+	for i := range t.MatchFields {
+		if t.MatchFields[i].Name == name {
+			return &t.MatchFields[i]
+		}
+	}
+	return nil
 }
 
 // DirectCounterForTableEntry 获取与指定表项关联的 DirectCounter 的值。
-func (t *Table) DirectCounterForTableEntry(matches []Match) *v1.Entity {
+func (t *Table) DirectCounterForTableEntry(matches map[string]Match) (*v1.Entity, error) {
+	fieldMatches, err := t.BuildMatches(matches)
+	if err != nil {
+		return nil, err
+	}
 	tableEntry := &v1.TableEntry{
 		TableId: t.ID,
-	}
-	for idx, m := range matches {
-		tableEntry.Match = append(tableEntry.Match, m.get(uint32(idx+1)))
+		Match:   fieldMatches,
 	}
 
 	dcEntry := &v1.DirectCounterEntry{
@@ -186,7 +441,7 @@ func (t *Table) DirectCounterForTableEntry(matches []Match) *v1.Entity {
 	entity := &v1.Entity{
 		Entity: &v1.Entity_DirectCounterEntry{DirectCounterEntry: dcEntry},
 	}
-	return entity
+	return entity, nil
 }
 
 // AllDirectCountersForTable 获取与特定表的所有条目相关的 DirectCounters 的值。
@@ -219,19 +474,105 @@ func AllDirectCounters() *v1.Entity {
 
 type Match interface {
 	get(ID uint32) *v1.FieldMatch
+	matchType() configv1.MatchField_MatchType
 }
 
-// ExactMatch 代表精确匹配（Exact Match）功能，包含一个字节切片 Value 用于存储要匹配的值
+// ExactMatch 代表精确匹配（Exact Match）功能，包含一个字节切片 Value 用于存储要匹配的值。
+// addrLen 为通过 NewIPExactMatch 创建时的地址长度，用于检查地址族，直接构造时为 0。
 type ExactMatch struct {
-	Value []byte
+	Value   []byte
+	addrLen int
 }
 
 // LpmMatch 代表最长前缀匹配（Longest Prefix Match）功能，包含两个字段：
 //   - Value：字节切片，用于存储要匹配的值。
 //   - PLen：整数，表示前缀长度。
+//
+// Value 可以是规范形式，也可以按字段完整位宽补齐前导零（例如 IPv4 为 4 字节）。
+// 发送前会按字段位宽清除前缀之外的位并转换为规范形式。
+// addrLen 为通过 NewLpmMatch 创建时的地址长度，用于检查地址族，直接构造时为 0。
 type LpmMatch struct {
-	Value []byte
-	PLen  int32
+	Value   []byte
+	PLen    int32
+	addrLen int
+}
+
+// NewIPExactMatch 根据 IPv4 或 IPv6 地址字符串创建精确匹配，例如 "10.0.0.1"、"2001:db8::1"。
+func NewIPExactMatch(ip string) (*ExactMatch, error) {
+	value, err := utils.IpToBinary(ip)
+	if err != nil {
+		return nil, err
+	}
+	return &ExactMatch{Value: value, addrLen: len(value)}, nil
+}
+
+// NewLpmMatch 根据 CIDR 格式的 IPv4 或 IPv6 前缀创建最长前缀匹配，例如 "10.0.0.0/8"、"2001:db8::/32"。
+func NewLpmMatch(prefix string) (*LpmMatch, error) {
+	value, pLen, err := utils.PrefixToBinary(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return &LpmMatch{Value: value, PLen: pLen, addrLen: len(value)}, nil
+}
+
+// checkValueLength 按 P4Info 中的字段位宽检查匹配值的字节长度。
+// 值可以是规范形式，也可以补齐到字段的完整字节宽度，但不能更长。
+// 由 IP 地址字符串创建的匹配还会检查地址族，见 checkAddressFamily；直接构造的字节值只检查长度。
+func checkValueLength(m Match, bitwidth int32) error {
+	var values [][]byte
+	switch v := m.(type) {
+	case *ExactMatch:
+		if err := checkAddressFamily(v.addrLen, bitwidth); err != nil {
+			return err
+		}
+		values = [][]byte{v.Value}
+	case *LpmMatch:
+		if err := checkAddressFamily(v.addrLen, bitwidth); err != nil {
+			return err
+		}
+		values = [][]byte{v.Value}
+	case *TernaryMatch:
+		values = [][]byte{v.Value, v.Mask}
+	case *RangeMatch:
+		values = [][]byte{v.Low, v.High}
+	case *OptionalMatch:
+		values = [][]byte{v.Value}
+	}
+
+	for _, value := range values {
+		if len(value) > byteWidth(bitwidth) {
+			return fmt.Errorf("%d-byte value is too long for %d-bit field", len(value), bitwidth)
+		}
+	}
+	return nil
+}
+
+// checkAddressFamily 检查由 IP 地址创建的匹配值是否属于另一地址族，
+// 避免将 IPv4 地址用于 128 位字段，或将 IPv6 地址用于 32 位字段。addrLen 为 0 时不检查。
+func checkAddressFamily(addrLen int, bitwidth int32) error {
+	switch {
+	case bitwidth == 8*net.IPv6len && addrLen == net.IPv4len:
+		return fmt.Errorf("IPv4 address supplied for %d-bit field", bitwidth)
+	case bitwidth == 8*net.IPv4len && addrLen == net.IPv6len:
+		return fmt.Errorf("IPv6 address supplied for %d-bit field", bitwidth)
+	}
+	return nil
+}
+
+// byteWidth 返回 bitwidth 位的值补齐到完整字节后的长度
+func byteWidth(bitwidth int32) int {
+	return int(bitwidth+7) / 8
+}
+
+// padValue 将交换机返回的规范形式值左侧补零至字段的完整字节宽度
+func padValue(b []byte, bitwidth int32) []byte {
+	n := byteWidth(bitwidth)
+	if len(b) >= n {
+		return b
+	}
+	result := make([]byte, n)
+	copy(result[n-len(b):], b)
+	return result
 }
 
 func (m *ExactMatch) get(ID uint32) *v1.FieldMatch {
@@ -250,80 +591,362 @@ func (m *LpmMatch) get(ID uint32) *v1.FieldMatch {
 		Value:     m.Value,
 		PrefixLen: m.PLen,
 	}
+	mf := &v1.FieldMatch{
+		FieldId:        ID,
+		FieldMatchType: &v1.FieldMatch_Lpm{Lpm: lpm},
+	}
+	return mf
+}
+
+// TernaryMatch 代表三元匹配（Ternary Match）功能，包含两个字段：
+//   - Value：字节切片，用于存储要匹配的值。
+//   - Mask：字节切片，表示掩码，只有掩码为 1 的位参与匹配。
+type TernaryMatch struct {
+	Value []byte
+	Mask  []byte
+}
+
+// RangeMatch 代表范围匹配（Range Match）功能，包含两个字段：
+//   - Low：字节切片，范围下界（包含）。
+//   - High：字节切片，范围上界（包含）。
+type RangeMatch struct {
+	Low  []byte
+	High []byte
+}
+
+// OptionalMatch 代表可选匹配（Optional Match）功能，包含一个字节切片 Value 用于存储要匹配的值。
+// 与 ExactMatch 不同，可选匹配的字段可以被省略，省略时表示通配。
+type OptionalMatch struct {
+	Value []byte
+}
+
+func (m *ExactMatch) matchType() configv1.MatchField_MatchType {
+	return configv1.MatchField_EXACT
+}
+
+func (m *LpmMatch) matchType() configv1.MatchField_MatchType {
+	return configv1.MatchField_LPM
+}
+
+func (m *TernaryMatch) matchType() configv1.MatchField_MatchType {
+	return configv1.MatchField_TERNARY
+}
+
+func (m *RangeMatch) matchType() configv1.MatchField_MatchType {
+	return configv1.MatchField_RANGE
+}
+
+func (m *OptionalMatch) matchType() configv1.MatchField_MatchType {
+	return configv1.MatchField_OPTIONAL
+}
+
+// get 按 P4Runtime 规范对值进行掩码处理（Value & Mask）。
+// 掩码全为 0 时表示通配，此时返回 nil，该字段不会出现在表项中。
+func (m *TernaryMatch) get(ID uint32) *v1.FieldMatch {
+	if isZero(m.Mask) {
+		return nil
+	}
 
-	firstByteMasked := int(m.PLen / 8)
-	if firstByteMasked != len(m.Value) {
-		i := firstByteMasked
-		r := m.PLen % 8
-		m.Value[i] = m.Value[i] & (0xff << (8 - r))
-		for i = i + 1; i < len(m.Value); i++ {
-			m.Value[i] = 0
+	value := make([]byte, len(m.Value))
+	offset := len(m.Mask) - len(m.Value)
+	for i := range m.Value {
+		if j := i + offset; j >= 0 && j < len(m.Mask) {
+			value[i] = m.Value[i] & m.Mask[j]
 		}
 	}
 
+	ternary := &v1.FieldMatch_Ternary{
+		Value: value,
+		Mask:  m.Mask,
+	}
 	mf := &v1.FieldMatch{
 		FieldId:        ID,
-		FieldMatchType: &v1.FieldMatch_Lpm{Lpm: lpm},
+		FieldMatchType: &v1.FieldMatch_Ternary_{Ternary: ternary},
 	}
+	return mf
+}
+
+func (m *RangeMatch) get(ID uint32) *v1.FieldMatch {
+	rangeMatch := &v1.FieldMatch_Range{
+		Low:  m.Low,
+		High: m.High,
+	}
+	mf := &v1.FieldMatch{
+		FieldId:        ID,
+		FieldMatchType: &v1.FieldMatch_Range_{Range: rangeMatch},
+	}
+	return mf
+}
 
+func (m *OptionalMatch) get(ID uint32) *v1.FieldMatch {
+	optional := &v1.FieldMatch_Optional{
+		Value: m.Value,
+	}
+	mf := &v1.FieldMatch{
+		FieldId:        ID,
+		FieldMatchType: &v1.FieldMatch_Optional_{Optional: optional},
+	}
 	return mf
 }
 
+// requiresPriority 判断匹配字段是否要求表项携带优先级。
+// 根据 P4Runtime 规范，包含 ternary、range 或 optional 匹配的表项必须设置大于 0 的 priority。
+func requiresPriority(m Match) bool {
+	switch m.(type) {
+	case *TernaryMatch, *RangeMatch, *OptionalMatch:
+		return true
+	default:
+		return false
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
 // TableEntryTransformer 用于将 JSON 数据转换为 P4 Runtime 兼容的数据格式。
 //   - 可以用于将应用层的 JSON 数据转换为底层 P4 Runtime 所需的格式。
-type TableEntryTransformer func(map[string]interface{}) ([]Match, [][]byte)
+//   - 返回的匹配字段和动作参数均以 P4Info 中的名称为键。
+type TableEntryTransformer func(map[string]interface{}) (map[string]Match, map[string][]byte)
+
+// buildEntry 根据动作、匹配字段和优先级构造一个 TableEntry。
+//   - tableAction 为 nil 时不设置动作，用于删除和读取等只需要表项键的场景。
+//   - priority 必须非负；当 mfs 中包含 ternary、range 或 optional 匹配时必须大于 0。
+func (t *Table) buildEntry(tableAction *v1.TableAction, mfs map[string]Match, priority int32, opts ...EntryOption) (*v1.TableEntry, error) {
+	if priority < 0 {
+		return nil, fmt.Errorf("invalid priority %d for table %s", priority, t.Name)
+	}
+	if tableAction != nil {
+		if err := t.checkTableAction(tableAction); err != nil {
+			return nil, err
+		}
+	}
+	for _, mf := range mfs {
+		if requiresPriority(mf) && priority == 0 {
+			return nil, fmt.Errorf("table %s: entries with ternary, range or optional matches require a priority", t.Name)
+		}
+	}
+
+	fieldMatches, err := t.BuildMatches(mfs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 创建一个 TableEntry 对象，设置表的 ID、动作、匹配字段以及优先级。
+	entry := &v1.TableEntry{
+		TableId:  t.ID,
+		Action:   tableAction,
+		Match:    fieldMatches,
+		Priority: priority,
+	}
+
+	for _, opt := range opts {
+		opt(entry)
+	}
+	if entry.IdleTimeoutNs < 0 {
+		return nil, fmt.Errorf("invalid idle timeout %dns for table %s", entry.IdleTimeoutNs, t.Name)
+	}
+	if entry.IdleTimeoutNs > 0 && !t.IdleTimeout {
+		return nil, fmt.Errorf("table %s does not support idle timeout", t.Name)
+	}
+
+	return entry, nil
+}
+
+// checkTableAction 检查动作类型与表的实现方式是否一致：直接表只接受直接动作，
+// 通过 action profile 或 action selector 实现的表只接受 member、group 或 one-shot 动作集。
+func (t *Table) checkTableAction(tableAction *v1.TableAction) error {
+	_, direct := tableAction.GetType().(*v1.TableAction_Action)
+	if direct && t.ImplementationID != 0 {
+		return fmt.Errorf("table %s is implemented by an action profile and does not accept direct actions", t.Name)
+	}
+	if !direct && t.ImplementationID == 0 {
+		return fmt.Errorf("table %s is not implemented by an action profile and only accepts direct actions", t.Name)
+	}
+	return nil
+}
+
+// directTableAction 创建一个 TableAction 对象，将 directAction 包装在其中，表示要在表上执行的操作。
+func directTableAction(action *Action, params map[string][]byte) (*v1.TableAction, error) {
+	actionParams, err := action.BuildParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	directAction := &v1.Action{
+		ActionId: action.ID,
+		Params:   actionParams,
+	}
+	return &v1.TableAction{
+		Type: &v1.TableAction_Action{Action: directAction},
+	}, nil
+}
+
+func tableEntryUpdate(updateType v1.Update_Type, entry *v1.TableEntry) *v1.Update {
+	return &v1.Update{
+		Type: updateType,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_TableEntry{TableEntry: entry},
+		},
+	}
+}
 
 // InsertEntry 插入一个条目
-// 功能：该方法的目的是创建并返回一个 P4 Runtime 更新请求，表示要插入或修改表中的条目。
+// 功能：该方法的目的是创建并返回一个 P4 Runtime 更新请求，表示要向表中插入新的条目。
 //   - 输入参数：
-//   - actionID uint32：表示要执行的动作的唯一标识符，通常与某个特定的动作（如转发、丢弃等）相关联。
-//   - mfs []Match：一个 Match 接口的切片，定义了条目的匹配条件。可以是精确匹配、最长前缀匹配等。
-//   - params [][]byte：与动作相关的参数，通常是与特定操作相关的值。
-func (t *Table) InsertEntry(actionID uint32, mfs []Match, params [][]byte) *v1.Update {
-	directAction := &v1.Action{
-		ActionId: actionID,
+//   - action *Action：表示要执行的动作（如转发、丢弃等），参数 ID 由其 P4Info 元数据解析。
+//   - mfs map[string]Match：以字段名称为键的匹配条件。可以是精确匹配、最长前缀匹配等。
+//   - params map[string][]byte：以参数名称为键的动作参数值。
+//   - priority int32：表项优先级，当 mfs 中包含 ternary、range 或 optional 匹配时必须大于 0。
+func (t *Table) InsertEntry(action *Action, mfs map[string]Match, params map[string][]byte, priority int32, opts ...EntryOption) (*v1.Update, error) {
+	tableAction, err := directTableAction(action, params)
+	if err != nil {
+		return nil, err
+	}
+	return t.InsertEntryWithAction(tableAction, mfs, priority, opts...)
+}
+
+// InsertEntryWithAction 插入一个使用任意 TableAction 的条目，
+// 用于 action profile member、action selector group 或 one-shot 动作集。
+func (t *Table) InsertEntryWithAction(tableAction *v1.TableAction, mfs map[string]Match, priority int32, opts ...EntryOption) (*v1.Update, error) {
+	entry, err := t.buildEntry(tableAction, mfs, priority, opts...)
+	if err != nil {
+		return nil, err
 	}
+	return tableEntryUpdate(v1.Update_INSERT, entry), nil
+}
 
-	for idx, p := range params {
-		param := &v1.Action_Param{
-			ParamId: uint32(idx + 1),
-			Value:   p,
-		}
-		directAction.Params = append(directAction.Params, param)
+// ModifyEntry 修改一个已存在条目的动作和参数，条目由 mfs 和 priority 确定。
+func (t *Table) ModifyEntry(action *Action, mfs map[string]Match, params map[string][]byte, priority int32, opts ...EntryOption) (*v1.Update, error) {
+	tableAction, err := directTableAction(action, params)
+	if err != nil {
+		return nil, err
 	}
+	return t.ModifyEntryWithAction(tableAction, mfs, priority, opts...)
+}
 
-	// 创建一个 TableAction 对象，将 directAction 包装在其中，表示要在表上执行的操作。
-	tableAction := &v1.TableAction{
-		Type: &v1.TableAction_Action{Action: directAction},
+// ModifyEntryWithAction 将一个已存在条目的动作修改为 tableAction。
+func (t *Table) ModifyEntryWithAction(tableAction *v1.TableAction, mfs map[string]Match, priority int32, opts ...EntryOption) (*v1.Update, error) {
+	entry, err := t.buildEntry(tableAction, mfs, priority, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return tableEntryUpdate(v1.Update_MODIFY, entry), nil
+}
+
+// DeleteEntry 删除由 mfs 和 priority 确定的条目。
+func (t *Table) DeleteEntry(mfs map[string]Match, priority int32) (*v1.Update, error) {
+	entry, err := t.buildEntry(nil, mfs, priority)
+	if err != nil {
+		return nil, err
 	}
+	return tableEntryUpdate(v1.Update_DELETE, entry), nil
+}
 
-	// 创建一个 TableEntry 对象，设置表的 ID、动作以及是否为默认动作。默认动作是指在没有其他匹配项的情况下执行的操作。
+// DeleteEntryByKey 删除与 entry 键（匹配字段和优先级）相同的条目，用于删除从交换机读回或通知中得到的条目。
+func (t *Table) DeleteEntryByKey(entry *v1.TableEntry) *v1.Update {
+	key := &v1.TableEntry{
+		TableId:  t.ID,
+		Match:    entry.Match,
+		Priority: entry.Priority,
+	}
+	return tableEntryUpdate(v1.Update_DELETE, key)
+}
+
+// SetDefaultAction 修改表的默认动作。默认动作是指在没有其他匹配项的情况下执行的操作。
+func (t *Table) SetDefaultAction(action *Action, params map[string][]byte) (*v1.Update, error) {
+	tableAction, err := directTableAction(action, params)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.checkTableAction(tableAction); err != nil {
+		return nil, err
+	}
 	entry := &v1.TableEntry{
 		TableId:         t.ID,
 		Action:          tableAction,
-		IsDefaultAction: (mfs == nil),
+		IsDefaultAction: true,
 	}
+	return tableEntryUpdate(v1.Update_MODIFY, entry), nil
+}
 
-	// 遍历 mfs，将每个 Match 对象转换为 P4 Runtime 所需的格式，并添加到 entry.Match 中。
-	for idx, mf := range mfs {
-		entry.Match = append(entry.Match, mf.get(uint32(idx+1)))
+// ResetDefaultAction 将表的默认动作恢复为 P4 程序中声明的初始值。
+// 根据 P4Runtime 规范，发送不带动作的默认表项 MODIFY 请求即可完成重置。
+func (t *Table) ResetDefaultAction() *v1.Update {
+	entry := &v1.TableEntry{
+		TableId:         t.ID,
+		IsDefaultAction: true,
 	}
+	return tableEntryUpdate(v1.Update_MODIFY, entry)
+}
 
-	// 根据 mfs 是否为 nil 来决定是插入新条目还是修改现有条目。若没有匹配条件，则视为修改现有条目。
-	var updateType v1.Update_Type
-	if mfs == nil {
-		updateType = v1.Update_MODIFY
-	} else {
-		updateType = v1.Update_INSERT
+// ReadEntry 读取由 mfs 和 priority 确定的单个条目。
+func (t *Table) ReadEntry(mfs map[string]Match, priority int32) (*v1.Entity, error) {
+	entry, err := t.buildEntry(nil, mfs, priority)
+	if err != nil {
+		return nil, err
 	}
-	update := &v1.Update{
-		Type: updateType,
-		Entity: &v1.Entity{
-			Entity: &v1.Entity_TableEntry{TableEntry: entry},
-		},
+	return &v1.Entity{
+		Entity: &v1.Entity_TableEntry{TableEntry: entry},
+	}, nil
+}
+
+// EntryKey 返回只包含匹配字段和优先级的表项，用于 direct meter 等以表项为键的实体。
+func (t *Table) EntryKey(mfs map[string]Match, priority int32) (*v1.TableEntry, error) {
+	return t.buildEntry(nil, mfs, priority)
+}
+
+// ReadAllEntries 读取表中的所有条目。
+func (t *Table) ReadAllEntries() *v1.Entity {
+	entry := &v1.TableEntry{
+		TableId: t.ID,
 	}
+	return &v1.Entity{
+		Entity: &v1.Entity_TableEntry{TableEntry: entry},
+	}
+}
 
-	return update
+// DecodeMatches 将交换机返回的 FieldMatch 还原为以字段名称为键的 Match。
+// 交换机返回的规范形式值会补齐到字段的完整字节宽度，因此可以直接用于 DeleteEntry 等需要表项键的操作。
+func (t *Table) DecodeMatches(fieldMatches []*v1.FieldMatch) (map[string]Match, error) {
+	result := make(map[string]Match, len(fieldMatches))
+	for _, fm := range fieldMatches {
+		field := t.matchFieldByID(fm.FieldId)
+		if field == nil {
+			return nil, fmt.Errorf("table %s has no match field with ID %d", t.Name, fm.FieldId)
+		}
+
+		bw := field.Bitwidth
+		switch m := fm.FieldMatchType.(type) {
+		case *v1.FieldMatch_Exact_:
+			result[field.Name] = &ExactMatch{Value: padValue(m.Exact.Value, bw)}
+		case *v1.FieldMatch_Lpm:
+			result[field.Name] = &LpmMatch{Value: padValue(m.Lpm.Value, bw), PLen: m.Lpm.PrefixLen}
+		case *v1.FieldMatch_Ternary_:
+			result[field.Name] = &TernaryMatch{Value: padValue(m.Ternary.Value, bw), Mask: padValue(m.Ternary.Mask, bw)}
+		case *v1.FieldMatch_Range_:
+			result[field.Name] = &RangeMatch{Low: padValue(m.Range.Low, bw), High: padValue(m.Range.High, bw)}
+		case *v1.FieldMatch_Optional_:
+			result[field.Name] = &OptionalMatch{Value: padValue(m.Optional.Value, bw)}
+		default:
+			return nil, fmt.Errorf("table %s: unsupported match type for field %s", t.Name, field.Name)
+		}
+	}
+	return result, nil
+}
+
+func (t *Table) matchFieldByID(id uint32) *MatchField {
+	for i := range t.MatchFields {
+		if t.MatchFields[i].ID == id {
+			return &t.MatchFields[i]
+		}
+	}
+	return nil
 }
 
 func (t *Table) Type() string {
@@ -339,8 +962,20 @@ func (t *Table) RegisterTransformer(transformer TableEntryTransformer) {
 }
 
 func GetTable(t *configv1.Table) Table {
+	matchFields := make([]MatchField, 0, len(t.MatchFields))
+	for _, mf := range t.MatchFields {
+		matchFields = append(matchFields, MatchField{
+			ID:        mf.Id,
+			Name:      mf.Name,
+			Bitwidth:  mf.Bitwidth,
+			MatchType: mf.GetMatchType(),
+		})
+	}
 	return Table{
-		Name: t.Preamble.Name,
-		ID:   t.Preamble.Id,
+		Name:             t.Preamble.Name,
+		ID:               t.Preamble.Id,
+		MatchFields:      matchFields,
+		IdleTimeout:      t.IdleTimeoutBehavior == configv1.Table_NOTIFY_CONTROL,
+		ImplementationID: t.ImplementationId,
 	}
 }
@@ -0,0 +1,275 @@
+package entity
+
+import (
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestBuildMatches(t *testing.T) {
+	table := &Table{
+		Name: "t",
+		MatchFields: []MatchField{
+			{ID: 1, Name: "exact12", Bitwidth: 12, MatchType: configv1.MatchField_EXACT},
+			{ID: 2, Name: "lpm12", Bitwidth: 12, MatchType: configv1.MatchField_LPM},
+			{ID: 3, Name: "lpm32", Bitwidth: 32, MatchType: configv1.MatchField_LPM},
+			{ID: 4, Name: "ternary16", Bitwidth: 16, MatchType: configv1.MatchField_TERNARY},
+			{ID: 5, Name: "range12", Bitwidth: 12, MatchType: configv1.MatchField_RANGE},
+			{ID: 6, Name: "optional8", Bitwidth: 8, MatchType: configv1.MatchField_OPTIONAL},
+			{ID: 7, Name: "lpm128", Bitwidth: 128, MatchType: configv1.MatchField_LPM},
+			{ID: 8, Name: "zero", Bitwidth: 0, MatchType: configv1.MatchField_RANGE},
+		},
+	}
+	ipv4, err := NewLpmMatch("10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exact := &ExactMatch{Value: []byte{0x00, 0x01}}
+	tests := []struct {
+		name    string
+		mfs     map[string]Match
+		want    []*v1.FieldMatch
+		wantErr bool
+	}{
+		{
+			name: "exact is canonicalized",
+			mfs:  map[string]Match{"exact12": &ExactMatch{Value: []byte{0x00, 0x0a}}},
+			want: []*v1.FieldMatch{exactField(1, 0x0a)},
+		},
+		{
+			name:    "exact overflows bitwidth",
+			mfs:     map[string]Match{"exact12": &ExactMatch{Value: []byte{0x10, 0x00}}},
+			wantErr: true,
+		},
+		{
+			name: "lpm on non byte-aligned field masks from the field's top bit",
+			mfs:  map[string]Match{"exact12": exact, "lpm12": &LpmMatch{Value: []byte{0x0f, 0xff}, PLen: 4}},
+			want: []*v1.FieldMatch{exactField(1, 0x01), lpmField(2, 4, 0x0f, 0x00)},
+		},
+		{
+			name: "lpm on byte-aligned field",
+			mfs:  map[string]Match{"exact12": exact, "lpm32": &LpmMatch{Value: []byte{10, 1, 2, 3}, PLen: 8}},
+			want: []*v1.FieldMatch{exactField(1, 0x01), lpmField(3, 8, 10, 0, 0, 0)},
+		},
+		{
+			name: "zero-length prefix is omitted",
+			mfs:  map[string]Match{"exact12": exact, "lpm32": &LpmMatch{Value: []byte{10, 0, 0, 0}, PLen: 0}},
+			want: []*v1.FieldMatch{exactField(1, 0x01)},
+		},
+		{
+			name:    "prefix longer than the field",
+			mfs:     map[string]Match{"exact12": exact, "lpm12": &LpmMatch{Value: []byte{0x0f, 0xff}, PLen: 13}},
+			wantErr: true,
+		},
+		{
+			name: "zero ternary mask is omitted",
+			mfs:  map[string]Match{"exact12": exact, "ternary16": &TernaryMatch{Value: []byte{0x12, 0x34}, Mask: []byte{0, 0}}},
+			want: []*v1.FieldMatch{exactField(1, 0x01)},
+		},
+		{
+			name: "ternary value is masked",
+			mfs:  map[string]Match{"exact12": exact, "ternary16": &TernaryMatch{Value: []byte{0x12, 0x34}, Mask: []byte{0x00, 0xff}}},
+			want: []*v1.FieldMatch{exactField(1, 0x01), ternaryField(4, []byte{0x34}, []byte{0xff})},
+		},
+		{
+			name: "full range is omitted",
+			mfs:  map[string]Match{"exact12": exact, "range12": &RangeMatch{Low: []byte{0}, High: []byte{0x0f, 0xff}}},
+			want: []*v1.FieldMatch{exactField(1, 0x01)},
+		},
+		{
+			name: "single value range is kept",
+			mfs:  map[string]Match{"exact12": exact, "range12": &RangeMatch{Low: []byte{0}, High: []byte{0}}},
+			want: []*v1.FieldMatch{exactField(1, 0x01), rangeField(5, []byte{0}, []byte{0})},
+		},
+		{
+			name:    "range low greater than high",
+			mfs:     map[string]Match{"exact12": exact, "range12": &RangeMatch{Low: []byte{2}, High: []byte{1}}},
+			wantErr: true,
+		},
+		{
+			name:    "zero bitwidth is rejected",
+			mfs:     map[string]Match{"exact12": exact, "zero": &RangeMatch{Low: []byte{0}, High: []byte{0}}},
+			wantErr: true,
+		},
+		{
+			name: "optional is canonicalized",
+			mfs:  map[string]Match{"exact12": exact, "optional8": &OptionalMatch{Value: []byte{0}}},
+			want: []*v1.FieldMatch{exactField(1, 0x01), optionalField(6, 0)},
+		},
+		{
+			name:    "value longer than the field",
+			mfs:     map[string]Match{"exact12": &ExactMatch{Value: []byte{0x00, 0x00, 0x0a}}},
+			wantErr: true,
+		},
+		{
+			name:    "ipv4 prefix on a 128-bit field",
+			mfs:     map[string]Match{"exact12": exact, "lpm128": ipv4},
+			wantErr: true,
+		},
+		{
+			name:    "missing exact field",
+			mfs:     map[string]Match{"optional8": &OptionalMatch{Value: []byte{7}}},
+			wantErr: true,
+		},
+		{
+			name:    "wrong match kind",
+			mfs:     map[string]Match{"exact12": &OptionalMatch{Value: []byte{7}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.BuildMatches(tt.mfs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BuildMatches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("BuildMatches() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !proto.Equal(got[i], tt.want[i]) {
+					t.Errorf("BuildMatches()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMatchesAddressFamily(t *testing.T) {
+	table := &Table{
+		Name: "t",
+		MatchFields: []MatchField{
+			{ID: 1, Name: "ipv4", Bitwidth: 32, MatchType: configv1.MatchField_EXACT},
+			{ID: 2, Name: "ipv6", Bitwidth: 128, MatchType: configv1.MatchField_LPM},
+		},
+	}
+	v4 := []byte{10, 0, 0, 1}
+	v6 := []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1}
+	mustExact := func(ip string) *ExactMatch {
+		m, err := NewIPExactMatch(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	mustLpm := func(prefix string) *LpmMatch {
+		m, err := NewLpmMatch(prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	tests := []struct {
+		name    string
+		ipv4    *ExactMatch
+		ipv6    *LpmMatch
+		wantErr bool
+	}{
+		{"matching families", mustExact("10.0.0.1"), mustLpm("2001:db8::1/128"), false},
+		{"raw values", &ExactMatch{Value: v4}, &LpmMatch{Value: v6, PLen: 128}, false},
+		{"canonical values", &ExactMatch{Value: []byte{1}}, &LpmMatch{Value: []byte{1}, PLen: 128}, false},
+		// 128 位字段上的 4 字节值不一定是 IPv4 地址，只按长度检查
+		{"4-byte raw value on 128-bit field", &ExactMatch{Value: v4}, &LpmMatch{Value: v4, PLen: 128}, false},
+		{"ipv4 address on 128-bit field", mustExact("10.0.0.1"), mustLpm("10.0.0.1"), true},
+		{"ipv6 address on 32-bit field", mustExact("2001:db8::1"), mustLpm("2001:db8::1/128"), true},
+		// 32 位字段上的 16 字节值由长度检查拒绝
+		{"16-byte raw value on 32-bit field", &ExactMatch{Value: v6}, &LpmMatch{Value: v6, PLen: 128}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := table.BuildMatches(map[string]Match{
+				"ipv4": tt.ipv4,
+				"ipv6": tt.ipv6,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("BuildMatches() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeMatchesPadsValues(t *testing.T) {
+	table := &Table{
+		Name:        "t",
+		MatchFields: []MatchField{{ID: 1, Name: "ipv6", Bitwidth: 128, MatchType: configv1.MatchField_EXACT}},
+	}
+	mfs, err := table.DecodeMatches([]*v1.FieldMatch{exactField(1, 10, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mfs["ipv6"].(*ExactMatch).Value; len(got) != 16 {
+		t.Fatalf("DecodeMatches() value = %x, want 16 bytes", got)
+	}
+	if _, err := table.BuildMatches(mfs); err != nil {
+		t.Errorf("BuildMatches() rejected a decoded key: %v", err)
+	}
+}
+
+func TestBuildMatchesKeepsCallerValues(t *testing.T) {
+	table := &Table{
+		Name:        "t",
+		MatchFields: []MatchField{{ID: 1, Name: "lpm", Bitwidth: 32, MatchType: configv1.MatchField_LPM}},
+	}
+	value := []byte{10, 1, 2, 3}
+	if _, err := table.BuildMatches(map[string]Match{"lpm": &LpmMatch{Value: value, PLen: 8}}); err != nil {
+		t.Fatal(err)
+	}
+	if value[1] != 1 || value[3] != 3 {
+		t.Errorf("BuildMatches() modified the caller's value: %v", value)
+	}
+}
+
+func TestTableActionMatchesImplementation(t *testing.T) {
+	fields := []MatchField{{ID: 1, Name: "exact", Bitwidth: 8, MatchType: configv1.MatchField_EXACT}}
+	direct := &Table{Name: "direct", MatchFields: fields}
+	indirect := &Table{Name: "indirect", MatchFields: fields, ImplementationID: 100}
+	action := &Action{ID: 1, Name: "forward"}
+	mfs := map[string]Match{"exact": &ExactMatch{Value: []byte{1}}}
+
+	tests := []struct {
+		name    string
+		table   *Table
+		build   func(t *Table) (*v1.Update, error)
+		wantErr bool
+	}{
+		{"direct action on direct table", direct, func(t *Table) (*v1.Update, error) { return t.InsertEntry(action, mfs, nil, 0) }, false},
+		{"direct action on action profile table", indirect, func(t *Table) (*v1.Update, error) { return t.InsertEntry(action, mfs, nil, 0) }, true},
+		{"direct modify on action profile table", indirect, func(t *Table) (*v1.Update, error) { return t.ModifyEntry(action, mfs, nil, 0) }, true},
+		{"direct default action on action profile table", indirect, func(t *Table) (*v1.Update, error) { return t.SetDefaultAction(action, nil) }, true},
+		{"member on action profile table", indirect, func(t *Table) (*v1.Update, error) { return t.InsertEntryWithAction(MemberTableAction(1), mfs, 0) }, false},
+		{"group on action profile table", indirect, func(t *Table) (*v1.Update, error) { return t.ModifyEntryWithAction(GroupTableAction(1), mfs, 0) }, false},
+		{"member on direct table", direct, func(t *Table) (*v1.Update, error) { return t.InsertEntryWithAction(MemberTableAction(1), mfs, 0) }, true},
+		{"delete ignores implementation", indirect, func(t *Table) (*v1.Update, error) { return t.DeleteEntry(mfs, 0) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.build(tt.table)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func exactField(id uint32, value ...byte) *v1.FieldMatch {
+	return &v1.FieldMatch{FieldId: id, FieldMatchType: &v1.FieldMatch_Exact_{Exact: &v1.FieldMatch_Exact{Value: value}}}
+}
+
+func lpmField(id uint32, prefixLen int32, value ...byte) *v1.FieldMatch {
+	return &v1.FieldMatch{FieldId: id, FieldMatchType: &v1.FieldMatch_Lpm{Lpm: &v1.FieldMatch_LPM{Value: value, PrefixLen: prefixLen}}}
+}
+
+func ternaryField(id uint32, value, mask []byte) *v1.FieldMatch {
+	return &v1.FieldMatch{FieldId: id, FieldMatchType: &v1.FieldMatch_Ternary_{Ternary: &v1.FieldMatch_Ternary{Value: value, Mask: mask}}}
+}
+
+func rangeField(id uint32, low, high []byte) *v1.FieldMatch {
+	return &v1.FieldMatch{FieldId: id, FieldMatchType: &v1.FieldMatch_Range_{Range: &v1.FieldMatch_Range{Low: low, High: high}}}
+}
+
+func optionalField(id uint32, value ...byte) *v1.FieldMatch {
+	return &v1.FieldMatch{FieldId: id, FieldMatchType: &v1.FieldMatch_Optional_{Optional: &v1.FieldMatch_Optional{Value: value}}}
+}
@@ -0,0 +1,116 @@
+package entity
+
+import (
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// Meter 保存 P4Info 中一个 meter 的信息，同时用于普通 meter 和 direct meter：
+//   - ID：meter 的唯一标识符。
+//   - Name：meter 的名称。
+//   - Size：meter 数组的大小，direct meter 为 0。
+//   - Unit：计量单位（字节或报文）。
+//   - TableID：direct meter 所属表的 ID，普通 meter 为 0。
+type Meter struct {
+	ID      uint32
+	Name    string
+	Size    int64
+	Unit    configv1.MeterSpec_Unit
+	TableID uint32
+}
+
+// IsDirect 判断该 meter 是否为绑定在表项上的 direct meter
+func (m *Meter) IsDirect() bool {
+	return m.TableID != 0
+}
+
+// ConfigWithIndex 用于设置特定索引处的 meter 配置，config 为 nil 时恢复默认配置（所有报文标记为绿色）
+func (m *Meter) ConfigWithIndex(index int64, config *v1.MeterConfig) *v1.Update {
+	entry := &v1.MeterEntry{
+		MeterId: m.ID,
+		Index:   &v1.Index{Index: index},
+		Config:  config,
+	}
+	return &v1.Update{
+		Type: v1.Update_MODIFY,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_MeterEntry{MeterEntry: entry},
+		},
+	}
+}
+
+// ReadWithIndex 用于读取特定索引处的 meter 配置
+func (m *Meter) ReadWithIndex(index int64) *v1.Entity {
+	entry := &v1.MeterEntry{
+		MeterId: m.ID,
+		Index:   &v1.Index{Index: index},
+	}
+	return &v1.Entity{
+		Entity: &v1.Entity_MeterEntry{MeterEntry: entry},
+	}
+}
+
+// Read 读取所有索引处的 meter 配置
+func (m *Meter) Read() *v1.Entity {
+	entry := &v1.MeterEntry{
+		MeterId: m.ID,
+	}
+	return &v1.Entity{
+		Entity: &v1.Entity_MeterEntry{MeterEntry: entry},
+	}
+}
+
+// ConfigForTableEntry 用于设置与表项关联的 direct meter 配置，config 为 nil 时恢复默认配置
+func (m *Meter) ConfigForTableEntry(tableEntry *v1.TableEntry, config *v1.MeterConfig) *v1.Update {
+	entry := &v1.DirectMeterEntry{
+		TableEntry: tableEntry,
+		Config:     config,
+	}
+	return &v1.Update{
+		Type: v1.Update_MODIFY,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_DirectMeterEntry{DirectMeterEntry: entry},
+		},
+	}
+}
+
+// ReadForTableEntry 读取与表项关联的 direct meter 配置
+func (m *Meter) ReadForTableEntry(tableEntry *v1.TableEntry) *v1.Entity {
+	entry := &v1.DirectMeterEntry{
+		TableEntry: tableEntry,
+	}
+	return &v1.Entity{
+		Entity: &v1.Entity_DirectMeterEntry{DirectMeterEntry: entry},
+	}
+}
+
+// ReadAllForTable 读取所属表中所有表项的 direct meter 配置
+func (m *Meter) ReadAllForTable() *v1.Entity {
+	return m.ReadForTableEntry(&v1.TableEntry{TableId: m.TableID})
+}
+
+func (m *Meter) Type() string {
+	return "METER"
+}
+
+func (m *Meter) GetID() uint32 {
+	return m.ID
+}
+
+func GetMeter(meter *configv1.Meter) Meter {
+	return Meter{
+		ID:   meter.Preamble.Id,
+		Name: meter.Preamble.Name,
+		Size: meter.Size,
+		Unit: meter.GetSpec().GetUnit(),
+	}
+}
+
+func GetDirectMeter(meter *configv1.DirectMeter) Meter {
+	return Meter{
+		ID:      meter.Preamble.Id,
+		Name:    meter.Preamble.Name,
+		Unit:    meter.GetSpec().GetUnit(),
+		TableID: meter.DirectTableId,
+	}
+}
@@ -0,0 +1,95 @@
+package entity
+
+import (
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestGetMeter(t *testing.T) {
+	tests := []struct {
+		name       string
+		got        Meter
+		want       Meter
+		wantDirect bool
+	}{
+		{
+			name: "indexed meter",
+			got: GetMeter(&configv1.Meter{
+				Preamble: &configv1.Preamble{Id: 1, Name: "ingress.tenant_meter"},
+				Spec:     &configv1.MeterSpec{Unit: configv1.MeterSpec_BYTES},
+				Size:     128,
+			}),
+			want: Meter{ID: 1, Name: "ingress.tenant_meter", Size: 128, Unit: configv1.MeterSpec_BYTES},
+		},
+		{
+			name: "direct meter",
+			got: GetDirectMeter(&configv1.DirectMeter{
+				Preamble:      &configv1.Preamble{Id: 2, Name: "ingress.acl_meter"},
+				Spec:          &configv1.MeterSpec{Unit: configv1.MeterSpec_PACKETS},
+				DirectTableId: 10,
+			}),
+			want:       Meter{ID: 2, Name: "ingress.acl_meter", Unit: configv1.MeterSpec_PACKETS, TableID: 10},
+			wantDirect: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %+v, want %+v", tt.got, tt.want)
+			}
+			if tt.got.IsDirect() != tt.wantDirect {
+				t.Errorf("IsDirect() = %v, want %v", tt.got.IsDirect(), tt.wantDirect)
+			}
+		})
+	}
+}
+
+func TestMeterUpdates(t *testing.T) {
+	meter := &Meter{ID: 1, Name: "ingress.tenant_meter", Size: 128}
+	direct := &Meter{ID: 2, Name: "ingress.acl_meter", TableID: 10}
+	config := &v1.MeterConfig{Cir: 1000, Cburst: 100, Pir: 2000, Pburst: 200}
+	key := &v1.TableEntry{TableId: 10, Match: []*v1.FieldMatch{{FieldId: 1}}}
+
+	meterEntity := func(entry *v1.MeterEntry) *v1.Entity {
+		return &v1.Entity{Entity: &v1.Entity_MeterEntry{MeterEntry: entry}}
+	}
+	directEntity := func(entry *v1.DirectMeterEntry) *v1.Entity {
+		return &v1.Entity{Entity: &v1.Entity_DirectMeterEntry{DirectMeterEntry: entry}}
+	}
+	modify := func(e *v1.Entity) *v1.Update {
+		return &v1.Update{Type: v1.Update_MODIFY, Entity: e}
+	}
+
+	tests := []struct {
+		name string
+		got  proto.Message
+		want proto.Message
+	}{
+		{"config at index", meter.ConfigWithIndex(5, config),
+			modify(meterEntity(&v1.MeterEntry{MeterId: 1, Index: &v1.Index{Index: 5}, Config: config}))},
+		{"reset at index", meter.ConfigWithIndex(5, nil),
+			modify(meterEntity(&v1.MeterEntry{MeterId: 1, Index: &v1.Index{Index: 5}}))},
+		{"read at index", meter.ReadWithIndex(5),
+			meterEntity(&v1.MeterEntry{MeterId: 1, Index: &v1.Index{Index: 5}})},
+		{"read all indexes", meter.Read(),
+			meterEntity(&v1.MeterEntry{MeterId: 1})},
+		{"config on table entry", direct.ConfigForTableEntry(key, config),
+			modify(directEntity(&v1.DirectMeterEntry{TableEntry: key, Config: config}))},
+		{"reset on table entry", direct.ConfigForTableEntry(key, nil),
+			modify(directEntity(&v1.DirectMeterEntry{TableEntry: key}))},
+		{"read on table entry", direct.ReadForTableEntry(key),
+			directEntity(&v1.DirectMeterEntry{TableEntry: key})},
+		{"read all table entries", direct.ReadAllForTable(),
+			directEntity(&v1.DirectMeterEntry{TableEntry: &v1.TableEntry{TableId: 10}})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !proto.Equal(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
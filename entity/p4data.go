@@ -0,0 +1,600 @@
+package entity
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/utils"
+)
+
+// ValueField 是 type_spec 不是 struct 或 tuple（例如 bit<32>）时唯一成员的名称。
+const ValueField = "value"
+
+// DataField 描述 P4Info type_spec 中的一个成员，例如 digest 结构体中的一个字段。
+//   - Name：成员名称；tuple 成员使用其下标作为名称。
+//   - Bitwidth：标量成员的位宽，bool 类型为 1。
+//   - Bool：成员是否为 bool 类型。
+//   - Signed：成员是否为 int<W> 类型，编解码时按二进制补码处理。
+//   - Varbit：成员是否为 varbit<W> 类型，此时 Bitwidth 为最大位宽。
+//   - Kind：成员是标量，还是嵌套的 struct、tuple 或 header。
+//   - Members：嵌套成员的子成员列表，标量成员为 nil。
+type DataField struct {
+	Name     string
+	Bitwidth int32
+	Bool     bool
+	Signed   bool
+	Varbit   bool
+	Kind     DataKind
+	Members  []DataField
+}
+
+// DataKind 区分标量成员和嵌套成员
+type DataKind int
+
+const (
+	// ScalarData 是 bitstring 或 bool 成员
+	ScalarData DataKind = iota
+	// StructData 是嵌套的 struct 成员
+	StructData
+	// TupleData 是嵌套的 tuple 成员
+	TupleData
+	// HeaderData 是嵌套的 header 成员
+	HeaderData
+)
+
+// getDataFields 根据 P4Info 中的 type_spec 展开成员列表，struct 和 header 名称通过 typeInfo 解析。
+// 目前支持 bitstring、bool 以及由它们组成的（可以嵌套的）struct、tuple 和 header。
+// type_spec 中含有不支持的类型时返回 nil。
+func getDataFields(spec *configv1.P4DataTypeSpec, typeInfo *configv1.P4TypeInfo) []DataField {
+	field, ok := dataField(ValueField, spec, typeInfo)
+	if !ok {
+		return nil
+	}
+	if field.Kind == StructData || field.Kind == TupleData {
+		return field.Members
+	}
+	return []DataField{field}
+}
+
+// dataField 将名为 name 的成员的 type_spec 展开为 DataField，嵌套的成员递归展开。
+func dataField(name string, spec *configv1.P4DataTypeSpec, typeInfo *configv1.P4TypeInfo) (DataField, bool) {
+	switch s := spec.GetTypeSpec().(type) {
+	case *configv1.P4DataTypeSpec_Bitstring:
+		return bitstringField(name, s.Bitstring)
+	case *configv1.P4DataTypeSpec_Bool:
+		return DataField{Name: name, Bitwidth: 1, Bool: true}, true
+	case *configv1.P4DataTypeSpec_Struct:
+		structSpec, ok := typeInfo.GetStructs()[s.Struct.GetName()]
+		if !ok {
+			return DataField{}, false
+		}
+		members := make([]DataField, 0, len(structSpec.Members))
+		for _, m := range structSpec.Members {
+			member, ok := dataField(m.Name, m.TypeSpec, typeInfo)
+			if !ok {
+				return DataField{}, false
+			}
+			members = append(members, member)
+		}
+		return DataField{Name: name, Kind: StructData, Members: members}, true
+	case *configv1.P4DataTypeSpec_Tuple:
+		members := make([]DataField, 0, len(s.Tuple.Members))
+		for idx, m := range s.Tuple.Members {
+			member, ok := dataField(strconv.Itoa(idx), m, typeInfo)
+			if !ok {
+				return DataField{}, false
+			}
+			members = append(members, member)
+		}
+		return DataField{Name: name, Kind: TupleData, Members: members}, true
+	case *configv1.P4DataTypeSpec_Header:
+		headerSpec, ok := typeInfo.GetHeaders()[s.Header.GetName()]
+		if !ok {
+			return DataField{}, false
+		}
+		members := make([]DataField, 0, len(headerSpec.Members))
+		for _, m := range headerSpec.Members {
+			member, ok := bitstringField(m.Name, m.TypeSpec)
+			if !ok {
+				return DataField{}, false
+			}
+			members = append(members, member)
+		}
+		return DataField{Name: name, Kind: HeaderData, Members: members}, true
+	default:
+		return DataField{}, false
+	}
+}
+
+// bitstringField 返回 bit<W>、int<W> 或 varbit<W> 类型的成员
+func bitstringField(name string, spec *configv1.P4BitstringLikeTypeSpec) (DataField, bool) {
+	switch {
+	case spec.GetBit() != nil:
+		return DataField{Name: name, Bitwidth: spec.GetBit().Bitwidth}, true
+	case spec.GetInt() != nil:
+		return DataField{Name: name, Bitwidth: spec.GetInt().Bitwidth, Signed: true}, true
+	case spec.GetVarbit() != nil:
+		return DataField{Name: name, Bitwidth: spec.GetVarbit().MaxBitwidth, Varbit: true}, true
+	default:
+		return DataField{}, false
+	}
+}
+
+// structMembers 返回 struct 或 tuple 类型 P4Data 的成员，标量类型的 P4Data 作为唯一的成员返回。
+// 成员数量必须与 fields 一致。
+func structMembers(fields []DataField, data *v1.P4Data) ([]*v1.P4Data, error) {
+	var members []*v1.P4Data
+	switch d := data.GetData().(type) {
+	case *v1.P4Data_Struct:
+		members = d.Struct.Members
+	case *v1.P4Data_Tuple:
+		members = d.Tuple.Members
+	default:
+		members = []*v1.P4Data{data}
+	}
+
+	if len(members) != len(fields) {
+		return nil, fmt.Errorf("expected %d members, got %d", len(fields), len(members))
+	}
+	return members, nil
+}
+
+// nestedMembers 返回嵌套成员的子成员，header 的每个字节串作为一个 bitstring 成员返回。
+// 无效的 header 返回 valid 为 false。
+func nestedMembers(field DataField, data *v1.P4Data) (members []*v1.P4Data, valid bool, err error) {
+	switch d := data.GetData().(type) {
+	case *v1.P4Data_Struct:
+		if field.Kind == StructData {
+			members = d.Struct.Members
+			valid = true
+		}
+	case *v1.P4Data_Tuple:
+		if field.Kind == TupleData {
+			members = d.Tuple.Members
+			valid = true
+		}
+	case *v1.P4Data_Header:
+		if field.Kind == HeaderData {
+			if !d.Header.IsValid {
+				return nil, false, nil
+			}
+			for _, b := range d.Header.Bitstrings {
+				members = append(members, &v1.P4Data{Data: &v1.P4Data_Bitstring{Bitstring: b}})
+			}
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, false, fmt.Errorf("member %s has unexpected P4Data type %T", field.Name, data.GetData())
+	}
+	if len(members) != len(field.Members) {
+		return nil, false, fmt.Errorf("member %s: expected %d members, got %d", field.Name, len(field.Members), len(members))
+	}
+	return members, true, nil
+}
+
+// memberBytes 返回成员的字节串，bool 成员返回单个字节 0x00 或 0x01。
+func memberBytes(field DataField, data *v1.P4Data) ([]byte, error) {
+	switch d := data.GetData().(type) {
+	case *v1.P4Data_Bitstring:
+		return d.Bitstring, nil
+	case *v1.P4Data_Varbit:
+		return d.Varbit.Bitstring, nil
+	case *v1.P4Data_Bool:
+		if d.Bool {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	default:
+		return nil, fmt.Errorf("member %s has unsupported P4Data type %T", field.Name, d)
+	}
+}
+
+// decodeP4Data 将 P4Data 按成员列表解码为以成员名称为键的值，值的类型见 decodeMember。
+func decodeP4Data(fields []DataField, data *v1.P4Data) (map[string]interface{}, error) {
+	members, err := structMembers(fields, data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeMembers(fields, members)
+}
+
+func decodeMembers(fields []DataField, members []*v1.P4Data) (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(fields))
+	for idx, m := range members {
+		value, err := decodeMember(fields[idx], m)
+		if err != nil {
+			return nil, err
+		}
+		result[fields[idx].Name] = value
+	}
+	return result, nil
+}
+
+// decodeMember 按成员的类型解码单个成员：
+//   - bool 成员解码为 bool。
+//   - bit<W> 成员在 W 不超过 64 时解码为 uint64，否则解码为 *big.Int。
+//   - int<W> 成员按二进制补码做符号扩展，W 不超过 64 时解码为 int64，否则解码为 *big.Int。
+//   - 嵌套的 struct、tuple 和 header 成员递归解码为 map[string]interface{}，无效的 header 解码为 nil map。
+func decodeMember(field DataField, data *v1.P4Data) (interface{}, error) {
+	if field.Kind != ScalarData {
+		members, valid, err := nestedMembers(field, data)
+		if err != nil || !valid {
+			return map[string]interface{}(nil), err
+		}
+		values, err := decodeMembers(field.Members, members)
+		if err != nil {
+			return nil, fmt.Errorf("member %s: %v", field.Name, err)
+		}
+		return values, nil
+	}
+	if field.Bool {
+		d, ok := data.GetData().(*v1.P4Data_Bool)
+		if !ok {
+			return nil, fmt.Errorf("member %s: expected bool, got %T", field.Name, data.GetData())
+		}
+		return d.Bool, nil
+	}
+
+	b, err := memberBytes(field, data)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	switch {
+	case field.Signed && field.Bitwidth <= 64:
+		value, err = utils.DecodeInt64(b, field.Bitwidth)
+	case field.Signed:
+		value, err = utils.DecodeSignedBigInt(b, field.Bitwidth)
+	case field.Bitwidth <= 64:
+		value, err = utils.DecodeUint64(b, field.Bitwidth)
+	default:
+		value, err = utils.DecodeBigInt(b, field.Bitwidth)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("member %s: %v", field.Name, err)
+	}
+	return value, nil
+}
+
+// encodeP4Data 将以成员名称为键的值按 type_spec 编码为 P4Data，是 decodeP4Data 的逆过程。
+// 每个成员都必须提供：bool 成员的值必须为 bool；嵌套成员的值必须为 map[string]interface{}，
+// header 成员的值为 nil 时编码为无效的 header；int<W> 成员的值见 utils.EncodeSigned，
+// 其他成员的值可以是 utils.Encode 支持的任意类型。varbit<W> 成员编码为位宽为 W 的 P4Data_Varbit。
+func encodeP4Data(spec *configv1.P4DataTypeSpec, fields []DataField, values map[string]interface{}) (*v1.P4Data, error) {
+	if fields == nil {
+		return nil, fmt.Errorf("unsupported type_spec")
+	}
+	members, err := encodeMembers(fields, values)
+	if err != nil {
+		return nil, err
+	}
+
+	switch spec.GetTypeSpec().(type) {
+	case *configv1.P4DataTypeSpec_Struct:
+		return &v1.P4Data{Data: &v1.P4Data_Struct{Struct: &v1.P4StructLike{Members: members}}}, nil
+	case *configv1.P4DataTypeSpec_Tuple:
+		return &v1.P4Data{Data: &v1.P4Data_Tuple{Tuple: &v1.P4StructLike{Members: members}}}, nil
+	default:
+		return members[0], nil
+	}
+}
+
+func encodeMembers(fields []DataField, values map[string]interface{}) ([]*v1.P4Data, error) {
+	for name := range values {
+		if !hasDataField(fields, name) {
+			return nil, fmt.Errorf("unknown member %s", name)
+		}
+	}
+
+	members := make([]*v1.P4Data, 0, len(fields))
+	for _, f := range fields {
+		value, ok := values[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("missing member %s", f.Name)
+		}
+		member, err := encodeMember(f, value)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// encodeMember 编码单个成员，是 decodeMember 的逆过程
+func encodeMember(field DataField, value interface{}) (*v1.P4Data, error) {
+	if field.Kind != ScalarData {
+		if field.Kind == HeaderData && isNilMap(value) {
+			return &v1.P4Data{Data: &v1.P4Data_Header{Header: &v1.P4Header{IsValid: false}}}, nil
+		}
+		values, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("member %s: expected map[string]interface{}, got %T", field.Name, value)
+		}
+		members, err := encodeMembers(field.Members, values)
+		if err != nil {
+			return nil, fmt.Errorf("member %s: %v", field.Name, err)
+		}
+		switch field.Kind {
+		case StructData:
+			return &v1.P4Data{Data: &v1.P4Data_Struct{Struct: &v1.P4StructLike{Members: members}}}, nil
+		case TupleData:
+			return &v1.P4Data{Data: &v1.P4Data_Tuple{Tuple: &v1.P4StructLike{Members: members}}}, nil
+		default:
+			bitstrings := make([][]byte, 0, len(members))
+			for idx, m := range members {
+				b, err := memberBytes(field.Members[idx], m)
+				if err != nil {
+					return nil, err
+				}
+				bitstrings = append(bitstrings, b)
+			}
+			return &v1.P4Data{Data: &v1.P4Data_Header{Header: &v1.P4Header{IsValid: true, Bitstrings: bitstrings}}}, nil
+		}
+	}
+	if field.Bool {
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("member %s: expected bool, got %T", field.Name, value)
+		}
+		return &v1.P4Data{Data: &v1.P4Data_Bool{Bool: b}}, nil
+	}
+
+	var canonical []byte
+	var err error
+	if field.Signed {
+		canonical, err = utils.EncodeSigned(value, field.Bitwidth)
+	} else {
+		canonical, err = utils.Encode(value, field.Bitwidth)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("member %s: %v", field.Name, err)
+	}
+	if field.Varbit {
+		// 值的实际位宽未知，使用 P4Info 中声明的最大位宽，它总能容纳编码后的值
+		return &v1.P4Data{Data: &v1.P4Data_Varbit{Varbit: &v1.P4Varbit{Bitstring: canonical, Bitwidth: field.Bitwidth}}}, nil
+	}
+	return &v1.P4Data{Data: &v1.P4Data_Bitstring{Bitstring: canonical}}, nil
+}
+
+// zeroValues 返回 fields 中每个成员的初始值，可以直接交给 encodeP4Data 编码：
+// bool 成员为 false，bitstring 成员为 0，struct 和 tuple 成员递归取初始值，header 成员为无效的 header（nil map）。
+func zeroValues(fields []DataField) map[string]interface{} {
+	values := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		switch {
+		case f.Kind == HeaderData:
+			values[f.Name] = map[string]interface{}(nil)
+		case f.Kind != ScalarData:
+			values[f.Name] = zeroValues(f.Members)
+		case f.Bool:
+			values[f.Name] = false
+		default:
+			values[f.Name] = uint64(0)
+		}
+	}
+	return values
+}
+
+// isNilMap 判断 value 是否为 nil 或 nil map
+func isNilMap(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	m, ok := value.(map[string]interface{})
+	return ok && m == nil
+}
+
+// decodeInto 将 P4Data 的成员写入 out 指向的结构体。
+// 结构体字段通过 `p4:"name"` 标签与成员对应，未加标签时按字段名（忽略大小写）匹配。
+// string 字段默认解码为十进制整数，可以在标签中指定格式，例如 `p4:"dst_addr,ipv4"` 或 `p4:",mac"`，
+// 格式名称见 utils.ParseStringFormat。
+// 支持的字段类型：各种整数、bool、string、[]byte、*big.Int、net.IP 和 net.HardwareAddr；
+// 嵌套的 struct、tuple 和 header 成员可以写入结构体、结构体指针或 map[string]interface{}，无效的 header 写入零值。
+func decodeInto(fields []DataField, data *v1.P4Data, out interface{}) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("decode target must be a pointer to struct, got %T", out)
+	}
+	members, err := structMembers(fields, data)
+	if err != nil {
+		return err
+	}
+	return setStruct(v.Elem(), fields, members)
+}
+
+// setStruct 将成员写入结构体 v 的对应字段
+func setStruct(v reflect.Value, fields []DataField, members []*v1.P4Data) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, option, _ := strings.Cut(sf.Tag.Get("p4"), ",")
+		if name == "-" {
+			continue
+		}
+		idx := findDataField(fields, name, sf.Name)
+		if idx < 0 {
+			continue
+		}
+		format := utils.FormatDecimal
+		if option != "" {
+			var err error
+			if format, err = utils.ParseStringFormat(option); err != nil {
+				return fmt.Errorf("field %s: %v", sf.Name, err)
+			}
+		}
+		if err := setValue(v.Field(i), fields[idx], members[idx], format); err != nil {
+			return fmt.Errorf("field %s: %v", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+func hasDataField(fields []DataField, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// findDataField 返回与结构体字段对应的成员下标，没有对应成员时返回 -1
+func findDataField(fields []DataField, tag, goName string) int {
+	for i := range fields {
+		if tag != "" && fields[i].Name == tag {
+			return i
+		}
+		if tag == "" && strings.EqualFold(fields[i].Name, goName) {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	ipType      = reflect.TypeOf(net.IP{})
+	macType     = reflect.TypeOf(net.HardwareAddr{})
+	bigIntType  = reflect.TypeOf(&big.Int{})
+	byteSliceTy = reflect.TypeOf([]byte{})
+	valueMapTy  = reflect.TypeOf(map[string]interface{}{})
+)
+
+// setValue 将成员写入 dst，format 为 string 字段使用的格式
+func setValue(dst reflect.Value, field DataField, data *v1.P4Data, format utils.StringFormat) error {
+	if field.Kind != ScalarData {
+		return setNested(dst, field, data)
+	}
+	b, err := memberBytes(field, data)
+	if err != nil {
+		return err
+	}
+	bitwidth := field.Bitwidth
+
+	switch dst.Type() {
+	case ipType:
+		var ip net.IP
+		if bitwidth == 8*net.IPv6len {
+			ip, err = utils.DecodeIPv6(b, bitwidth)
+		} else {
+			ip, err = utils.DecodeIPv4(b, bitwidth)
+		}
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(ip))
+		return nil
+	case macType:
+		mac, err := utils.DecodeMAC(b, bitwidth)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(mac))
+		return nil
+	case bigIntType:
+		v, err := decodeInteger(field, b)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(v))
+		return nil
+	case byteSliceTy:
+		dst.SetBytes(b)
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := decodeInteger(field, b)
+		if err != nil {
+			return err
+		}
+		if !v.IsUint64() || dst.OverflowUint(v.Uint64()) {
+			return fmt.Errorf("value %s overflows %s", v, dst.Type())
+		}
+		dst.SetUint(v.Uint64())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v, err := decodeInteger(field, b)
+		if err != nil {
+			return err
+		}
+		if !v.IsInt64() || dst.OverflowInt(v.Int64()) {
+			return fmt.Errorf("value %s overflows %s", v, dst.Type())
+		}
+		dst.SetInt(v.Int64())
+	case reflect.Bool:
+		v, err := utils.DecodeBigInt(b, bitwidth)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(v.Sign() != 0)
+	case reflect.String:
+		if field.Signed {
+			v, err := utils.DecodeSignedBigInt(b, bitwidth)
+			if err != nil {
+				return err
+			}
+			dst.SetString(v.String())
+			return nil
+		}
+		str, err := utils.DecodeString(b, bitwidth, format)
+		if err != nil {
+			return err
+		}
+		dst.SetString(str)
+	default:
+		return fmt.Errorf("unsupported field type %s", dst.Type())
+	}
+	return nil
+}
+
+// decodeInteger 将成员解码为整数，int<W> 成员按二进制补码做符号扩展
+func decodeInteger(field DataField, b []byte) (*big.Int, error) {
+	if field.Signed {
+		return utils.DecodeSignedBigInt(b, field.Bitwidth)
+	}
+	return utils.DecodeBigInt(b, field.Bitwidth)
+}
+
+// setNested 将嵌套成员写入结构体、结构体指针或 map[string]interface{}，无效的 header 写入零值
+func setNested(dst reflect.Value, field DataField, data *v1.P4Data) error {
+	members, valid, err := nestedMembers(field, data)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	switch {
+	case dst.Type() == valueMapTy:
+		values, err := decodeMembers(field.Members, members)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(values))
+		return nil
+	case dst.Kind() == reflect.Struct:
+		return setStruct(dst, field.Members, members)
+	case dst.Kind() == reflect.Ptr && dst.Type().Elem().Kind() == reflect.Struct:
+		v := reflect.New(dst.Type().Elem())
+		if err := setStruct(v.Elem(), field.Members, members); err != nil {
+			return err
+		}
+		dst.Set(v)
+		return nil
+	default:
+		return fmt.Errorf("unsupported field type %s for member %s", dst.Type(), field.Name)
+	}
+}
@@ -0,0 +1,439 @@
+package entity
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func bitSpec(bitwidth int32) *configv1.P4DataTypeSpec {
+	return &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Bitstring{Bitstring: bitLike(bitwidth, false)}}
+}
+
+func intSpec(bitwidth int32) *configv1.P4DataTypeSpec {
+	return &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Bitstring{Bitstring: bitLike(bitwidth, true)}}
+}
+
+func bitLike(bitwidth int32, signed bool) *configv1.P4BitstringLikeTypeSpec {
+	if signed {
+		return &configv1.P4BitstringLikeTypeSpec{TypeSpec: &configv1.P4BitstringLikeTypeSpec_Int{Int: &configv1.P4IntTypeSpec{Bitwidth: bitwidth}}}
+	}
+	return &configv1.P4BitstringLikeTypeSpec{TypeSpec: &configv1.P4BitstringLikeTypeSpec_Bit{Bit: &configv1.P4BitTypeSpec{Bitwidth: bitwidth}}}
+}
+
+func member(name string, spec *configv1.P4DataTypeSpec) *configv1.P4StructTypeSpec_Member {
+	return &configv1.P4StructTypeSpec_Member{Name: name, TypeSpec: spec}
+}
+
+// testDigestSpec 返回以下 digest 类型：
+//
+//	header h_t { bit<16> etype; int<4> s; }
+//	struct inner_t { int<70> big; bit<48> mac; }
+//	struct digest_t { bit<12> port; int<8> delta; bool flag; inner_t inner; h_t h; }
+func testDigestSpec() (*configv1.P4DataTypeSpec, *configv1.P4TypeInfo) {
+	named := func(name string) *configv1.P4NamedType { return &configv1.P4NamedType{Name: name} }
+	typeInfo := &configv1.P4TypeInfo{
+		Structs: map[string]*configv1.P4StructTypeSpec{
+			"inner_t": {Members: []*configv1.P4StructTypeSpec_Member{
+				member("big", intSpec(70)),
+				member("mac", bitSpec(48)),
+			}},
+			"digest_t": {Members: []*configv1.P4StructTypeSpec_Member{
+				member("port", bitSpec(12)),
+				member("delta", intSpec(8)),
+				member("flag", &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Bool{Bool: &configv1.P4BoolType{}}}),
+				member("inner", &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Struct{Struct: named("inner_t")}}),
+				member("h", &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Header{Header: named("h_t")}}),
+			}},
+		},
+		Headers: map[string]*configv1.P4HeaderTypeSpec{
+			"h_t": {Members: []*configv1.P4HeaderTypeSpec_Member{
+				{Name: "etype", TypeSpec: bitLike(16, false)},
+				{Name: "s", TypeSpec: bitLike(4, true)},
+			}},
+		},
+	}
+	return &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Struct{Struct: named("digest_t")}}, typeInfo
+}
+
+func bits(b ...byte) *v1.P4Data {
+	return &v1.P4Data{Data: &v1.P4Data_Bitstring{Bitstring: b}}
+}
+
+func structData(members ...*v1.P4Data) *v1.P4Data {
+	return &v1.P4Data{Data: &v1.P4Data_Struct{Struct: &v1.P4StructLike{Members: members}}}
+}
+
+func headerData(valid bool, bitstrings ...[]byte) *v1.P4Data {
+	return &v1.P4Data{Data: &v1.P4Data_Header{Header: &v1.P4Header{IsValid: valid, Bitstrings: bitstrings}}}
+}
+
+func TestGetDataFieldsNested(t *testing.T) {
+	spec, typeInfo := testDigestSpec()
+	fields := getDataFields(spec, typeInfo)
+	want := []DataField{
+		{Name: "port", Bitwidth: 12},
+		{Name: "delta", Bitwidth: 8, Signed: true},
+		{Name: "flag", Bitwidth: 1, Bool: true},
+		{Name: "inner", Kind: StructData, Members: []DataField{
+			{Name: "big", Bitwidth: 70, Signed: true},
+			{Name: "mac", Bitwidth: 48},
+		}},
+		{Name: "h", Kind: HeaderData, Members: []DataField{
+			{Name: "etype", Bitwidth: 16},
+			{Name: "s", Bitwidth: 4, Signed: true},
+		}},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("getDataFields() = %+v, want %+v", fields, want)
+	}
+
+	unknown := &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Struct{Struct: &configv1.P4NamedType{Name: "missing_t"}}}
+	if fields := getDataFields(unknown, typeInfo); fields != nil {
+		t.Errorf("getDataFields() = %+v for an unknown struct, want nil", fields)
+	}
+}
+
+func TestDecodeP4Data(t *testing.T) {
+	spec, typeInfo := testDigestSpec()
+	fields := getDataFields(spec, typeInfo)
+	minusOne70 := append([]byte{0x3f}, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)
+
+	tests := []struct {
+		name    string
+		data    *v1.P4Data
+		want    map[string]interface{}
+		wantErr bool
+	}{
+		{
+			name: "signed members are sign-extended and nested members are typed",
+			data: structData(bits(0x0f, 0xff), bits(0xfe), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: true}},
+				structData(bits(minusOne70...), bits(0x11, 0x22)),
+				headerData(true, []byte{0x08, 0x00}, []byte{0x08})),
+			want: map[string]interface{}{
+				"port":  uint64(0xfff),
+				"delta": int64(-2),
+				"flag":  true,
+				"inner": map[string]interface{}{"big": big.NewInt(-1), "mac": uint64(0x1122)},
+				"h":     map[string]interface{}{"etype": uint64(0x0800), "s": int64(-8)},
+			},
+		},
+		{
+			name: "invalid header decodes to a nil map",
+			data: structData(bits(1), bits(1), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: false}},
+				structData(bits(0), bits(0)), headerData(false)),
+			want: map[string]interface{}{
+				"port":  uint64(1),
+				"delta": int64(1),
+				"flag":  false,
+				"inner": map[string]interface{}{"big": big.NewInt(0), "mac": uint64(0)},
+				"h":     map[string]interface{}(nil),
+			},
+		},
+		{
+			name: "nested member count mismatch",
+			data: structData(bits(1), bits(1), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: false}},
+				structData(bits(0)), headerData(false)),
+			wantErr: true,
+		},
+		{
+			name: "header where a struct is expected",
+			data: structData(bits(1), bits(1), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: false}},
+				headerData(false), headerData(false)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeP4Data(fields, tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeP4Data() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !equalValues(got, tt.want) {
+				t.Errorf("decodeP4Data() = %v, want %v", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			again, err := encodeP4Data(spec, fields, got)
+			if err != nil {
+				t.Fatalf("encodeP4Data() error = %v", err)
+			}
+			if !proto.Equal(again, tt.data) {
+				t.Errorf("encodeP4Data(decodeP4Data()) = %v, want %v", again, tt.data)
+			}
+		})
+	}
+}
+
+// equalValues 比较解码得到的值，*big.Int 按数值比较，nil map 与空 map 不相等
+func equalValues(a, b interface{}) bool {
+	switch av := a.(type) {
+	case *big.Int:
+		bv, ok := b.(*big.Int)
+		return ok && av.Cmp(bv) == 0
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || (av == nil) != (bv == nil) || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if !equalValues(v, bv[k]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return reflect.DeepEqual(a, b)
+	}
+}
+
+func TestEncodeP4Data(t *testing.T) {
+	spec, typeInfo := testDigestSpec()
+	fields := getDataFields(spec, typeInfo)
+	values := func(override map[string]interface{}) map[string]interface{} {
+		v := map[string]interface{}{
+			"port":  10,
+			"delta": -1,
+			"flag":  true,
+			"inner": map[string]interface{}{"big": -2, "mac": "00:00:00:00:11:22"},
+			"h":     map[string]interface{}{"etype": 0x86dd, "s": -1},
+		}
+		for name, value := range override {
+			v[name] = value
+		}
+		return v
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]interface{}
+		want    *v1.P4Data
+		wantErr bool
+	}{
+		{
+			name:   "negative and nested values",
+			values: values(nil),
+			want: structData(bits(0x0a), bits(0xff), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: true}},
+				structData(bits(append([]byte{0x3f}, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe)...), bits(0x11, 0x22)),
+				headerData(true, []byte{0x86, 0xdd}, []byte{0x0f})),
+		},
+		{
+			name:   "nil header is encoded as invalid",
+			values: values(map[string]interface{}{"h": nil}),
+			want: structData(bits(0x0a), bits(0xff), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: true}},
+				structData(bits(append([]byte{0x3f}, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xfe)...), bits(0x11, 0x22)),
+				headerData(false)),
+		},
+		{
+			name:    "signed value out of range",
+			values:  values(map[string]interface{}{"delta": -129}),
+			wantErr: true,
+		},
+		{
+			name:    "negative value for unsigned member",
+			values:  values(map[string]interface{}{"port": -1}),
+			wantErr: true,
+		},
+		{
+			name:    "non-map value for nested member",
+			values:  values(map[string]interface{}{"inner": 1}),
+			wantErr: true,
+		},
+		{
+			name:    "missing nested member",
+			values:  values(map[string]interface{}{"inner": map[string]interface{}{"big": 1}}),
+			wantErr: true,
+		},
+		{
+			name:    "unknown member",
+			values:  values(map[string]interface{}{"bogus": 1}),
+			wantErr: true,
+		},
+		{
+			name:    "non-bool value for bool member",
+			values:  values(map[string]interface{}{"flag": 1}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeP4Data(spec, fields, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encodeP4Data() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("encodeP4Data() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	spec, typeInfo := testDigestSpec()
+	fields := getDataFields(spec, typeInfo)
+
+	type inner struct {
+		Big *big.Int
+		MAC net.HardwareAddr `p4:"mac"`
+	}
+	type header struct {
+		EtherType uint16 `p4:"etype"`
+		S         int8
+	}
+	type digest struct {
+		Port   uint16
+		Delta  int8
+		Flag   bool
+		Inner  inner
+		H      *header
+		Raw    map[string]interface{} `p4:"h"`
+		Ignore int                    `p4:"-"`
+	}
+
+	data := structData(bits(0x01, 0x00), bits(0x80), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: true}},
+		structData(bits(0x01), bits(0x11, 0x22)),
+		headerData(true, []byte{0x08, 0x00}, []byte{0x07}))
+	var got digest
+	if err := decodeInto(fields, data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := digest{
+		Port:  0x100,
+		Delta: -128,
+		Flag:  true,
+		Inner: inner{Big: big.NewInt(1), MAC: net.HardwareAddr{0, 0, 0, 0, 0x11, 0x22}},
+		H:     &header{EtherType: 0x0800, S: 7},
+		Raw:   map[string]interface{}{"etype": uint64(0x0800), "s": int64(7)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decodeInto() = %+v, want %+v", got, want)
+	}
+
+	invalid := structData(bits(0), bits(0), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: false}},
+		structData(bits(0), bits(0)), headerData(false))
+	if err := decodeInto(fields, invalid, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.H != nil || got.Raw != nil {
+		t.Errorf("decodeInto() kept an invalid header: %+v, %v", got.H, got.Raw)
+	}
+
+	var overflow struct {
+		Delta uint8
+	}
+	if err := decodeInto(fields, data, &overflow); err == nil {
+		t.Errorf("decodeInto() stored a negative int<8> in a uint8: %d", overflow.Delta)
+	}
+}
+
+func TestDecodeIntoStringFormat(t *testing.T) {
+	spec, typeInfo := testDigestSpec()
+	fields := getDataFields(spec, typeInfo)
+	data := structData(bits(0x01, 0x00), bits(0x80), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: true}},
+		structData(bits(0x01), bits(0x11, 0x22)),
+		headerData(true, []byte{0x08, 0x00}, []byte{0x07}))
+
+	type formatted struct {
+		Port    string
+		PortHex string `p4:"port,hex"`
+		Delta   string `p4:",hex"`
+	}
+	var got formatted
+	if err := decodeInto(fields, data, &got); err != nil {
+		t.Fatal(err)
+	}
+	// int<W> 成员总是解码为带符号的十进制整数
+	want := formatted{Port: "256", PortHex: "0x100", Delta: "-128"}
+	if got != want {
+		t.Errorf("decodeInto() = %+v, want %+v", got, want)
+	}
+
+	var unknown struct {
+		Port string `p4:"port,ip"`
+	}
+	if err := decodeInto(fields, data, &unknown); err == nil {
+		t.Errorf("decodeInto() accepted an unknown string format: %+v", unknown)
+	}
+}
+
+func TestEncodeVarbit(t *testing.T) {
+	varbit := &configv1.P4BitstringLikeTypeSpec{TypeSpec: &configv1.P4BitstringLikeTypeSpec_Varbit{Varbit: &configv1.P4VarbitTypeSpec{MaxBitwidth: 320}}}
+	varbitSpec := &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Bitstring{Bitstring: varbit}}
+	typeInfo := &configv1.P4TypeInfo{
+		Headers: map[string]*configv1.P4HeaderTypeSpec{
+			"opt_t": {Members: []*configv1.P4HeaderTypeSpec_Member{
+				{Name: "len", TypeSpec: bitLike(8, false)},
+				{Name: "data", TypeSpec: varbit},
+			}},
+		},
+	}
+	header := &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Header{Header: &configv1.P4NamedType{Name: "opt_t"}}}
+	tuple := &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Tuple{Tuple: &configv1.P4TupleTypeSpec{
+		Members: []*configv1.P4DataTypeSpec{varbitSpec, header},
+	}}}
+	varbitData := func(b ...byte) *v1.P4Data {
+		return &v1.P4Data{Data: &v1.P4Data_Varbit{Varbit: &v1.P4Varbit{Bitstring: b, Bitwidth: 320}}}
+	}
+
+	tests := []struct {
+		name    string
+		spec    *configv1.P4DataTypeSpec
+		values  map[string]interface{}
+		want    *v1.P4Data
+		wantErr bool
+	}{
+		{
+			name:   "scalar varbit",
+			spec:   varbitSpec,
+			values: map[string]interface{}{ValueField: "0x0102"},
+			want:   varbitData(0x01, 0x02),
+		},
+		{
+			name: "varbit in tuple and header",
+			spec: tuple,
+			values: map[string]interface{}{
+				"0": 7,
+				"1": map[string]interface{}{"len": 2, "data": []byte{0, 0xab, 0xcd}},
+			},
+			want: &v1.P4Data{Data: &v1.P4Data_Tuple{Tuple: &v1.P4StructLike{Members: []*v1.P4Data{
+				varbitData(0x07),
+				headerData(true, []byte{0x02}, []byte{0xab, 0xcd}),
+			}}}},
+		},
+		{
+			name:    "value wider than the maximum bitwidth",
+			spec:    varbitSpec,
+			values:  map[string]interface{}{ValueField: append([]byte{0x01}, make([]byte, 40)...)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := getDataFields(tt.spec, typeInfo)
+			got, err := encodeP4Data(tt.spec, fields, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("encodeP4Data() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.want == nil {
+				return
+			}
+			if !proto.Equal(got, tt.want) {
+				t.Errorf("encodeP4Data() = %v, want %v", got, tt.want)
+			}
+			values, err := decodeP4Data(fields, got)
+			if err != nil {
+				t.Fatalf("decodeP4Data() error = %v", err)
+			}
+			again, err := encodeP4Data(tt.spec, fields, values)
+			if err != nil || !proto.Equal(again, got) {
+				t.Errorf("encodeP4Data(decodeP4Data()) = %v, %v, want %v", again, err, got)
+			}
+		})
+	}
+}
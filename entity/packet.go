@@ -0,0 +1,123 @@
+package entity
+
+import (
+	"fmt"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"p4r/utils"
+)
+
+// ControllerPacketMetadata 描述 P4 程序中用 @controller_header 标注的报头，
+// 即 packet_in（交换机上送给控制器）和 packet_out（控制器下发给交换机）携带的元数据。
+//   - ID：报头的唯一标识符。
+//   - Name：报头名称，通常为 "packet_in" 或 "packet_out"。
+//   - Metadata：按报头布局排列的元数据字段。
+type ControllerPacketMetadata struct {
+	ID       uint32
+	Name     string
+	Metadata []PacketMetadataField
+}
+
+// PacketMetadataField 保存 P4Info 中单个元数据字段的 ID、名称和位宽。
+type PacketMetadataField struct {
+	ID       uint32
+	Name     string
+	Bitwidth int32
+}
+
+// Encode 根据字段名称将 metadata 转换为 P4Runtime 的 PacketMetadata，用于 PacketOut。
+// 每个字段都必须提供，且不允许出现 P4Info 中未声明的字段。
+func (cpm *ControllerPacketMetadata) Encode(metadata map[string][]byte) ([]*v1.PacketMetadata, error) {
+	for name := range metadata {
+		if cpm.field(name) == nil {
+			return nil, fmt.Errorf("%s has no metadata named %s", cpm.Name, name)
+		}
+	}
+
+	result := make([]*v1.PacketMetadata, 0, len(cpm.Metadata))
+	for _, f := range cpm.Metadata {
+		value, ok := metadata[f.Name]
+		if !ok {
+			return nil, fmt.Errorf("%s: missing metadata %s", cpm.Name, f.Name)
+		}
+		canonical, err := utils.Canonicalize(value, f.Bitwidth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: metadata %s: %v", cpm.Name, f.Name, err)
+		}
+		result = append(result, &v1.PacketMetadata{
+			MetadataId: f.ID,
+			Value:      canonical,
+		})
+	}
+	return result, nil
+}
+
+// Decode 将 PacketIn 中的 PacketMetadata 还原为以字段名称为键的值。
+func (cpm *ControllerPacketMetadata) Decode(metadata []*v1.PacketMetadata) (map[string][]byte, error) {
+	result := make(map[string][]byte, len(metadata))
+	for _, m := range metadata {
+		f := cpm.fieldByID(m.MetadataId)
+		if f == nil {
+			return nil, fmt.Errorf("%s has no metadata with ID %d", cpm.Name, m.MetadataId)
+		}
+		result[f.Name] = m.Value
+	}
+	return result, nil
+}
+
+// PacketOut 构造一条携带 payload 和元数据的 PacketOut 流消息。
+func (cpm *ControllerPacketMetadata) PacketOut(payload []byte, metadata map[string][]byte) (*v1.StreamMessageRequest, error) {
+	encoded, err := cpm.Encode(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &v1.StreamMessageRequest{
+		Update: &v1.StreamMessageRequest_Packet{Packet: &v1.PacketOut{
+			Payload:  payload,
+			Metadata: encoded,
+		}},
+	}, nil
+}
+
+func (cpm *ControllerPacketMetadata) field(name string) *PacketMetadataField {
+	for i := range cpm.Metadata {
+		if cpm.Metadata[i].Name == name {
+			return &cpm.Metadata[i]
+		}
+	}
+	return nil
+}
+
+func (cpm *ControllerPacketMetadata) fieldByID(id uint32) *PacketMetadataField {
+	for i := range cpm.Metadata {
+		if cpm.Metadata[i].ID == id {
+			return &cpm.Metadata[i]
+		}
+	}
+	return nil
+}
+
+func (cpm *ControllerPacketMetadata) Type() string {
+	return "CONTROLLER_PACKET_METADATA"
+}
+
+func (cpm *ControllerPacketMetadata) GetID() uint32 {
+	return cpm.ID
+}
+
+func GetControllerPacketMetadata(cpm *configv1.ControllerPacketMetadata) ControllerPacketMetadata {
+	fields := make([]PacketMetadataField, 0, len(cpm.Metadata))
+	for _, m := range cpm.Metadata {
+		fields = append(fields, PacketMetadataField{
+			ID:       m.Id,
+			Name:     m.Name,
+			Bitwidth: m.Bitwidth,
+		})
+	}
+	return ControllerPacketMetadata{
+		ID:       cpm.Preamble.Id,
+		Name:     cpm.Preamble.Name,
+		Metadata: fields,
+	}
+}
@@ -0,0 +1,135 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func testPacketOutMetadata() ControllerPacketMetadata {
+	return GetControllerPacketMetadata(&configv1.ControllerPacketMetadata{
+		Preamble: &configv1.Preamble{Id: 1, Name: "packet_out"},
+		Metadata: []*configv1.ControllerPacketMetadata_Metadata{
+			{Id: 1, Name: "egress_port", Bitwidth: 9},
+			{Id: 2, Name: "flags", Bitwidth: 7},
+		},
+	})
+}
+
+func TestControllerPacketMetadataEncode(t *testing.T) {
+	cpm := testPacketOutMetadata()
+	tests := []struct {
+		name     string
+		metadata map[string][]byte
+		want     []*v1.PacketMetadata
+		wantErr  bool
+	}{
+		{
+			name:     "encoded in P4Info order and canonicalized",
+			metadata: map[string][]byte{"flags": {0x00, 0x05}, "egress_port": {0x01, 0x00}},
+			want:     []*v1.PacketMetadata{{MetadataId: 1, Value: []byte{0x01, 0x00}}, {MetadataId: 2, Value: []byte{0x05}}},
+		},
+		{
+			name:     "zero value",
+			metadata: map[string][]byte{"egress_port": {}, "flags": {0x00}},
+			want:     []*v1.PacketMetadata{{MetadataId: 1, Value: []byte{0x00}}, {MetadataId: 2, Value: []byte{0x00}}},
+		},
+		{
+			name:     "missing metadata",
+			metadata: map[string][]byte{"egress_port": {0x01}},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown metadata",
+			metadata: map[string][]byte{"egress_port": {0x01}, "flags": {0x01}, "vlan": {0x01}},
+			wantErr:  true,
+		},
+		{
+			name:     "value overflows bitwidth",
+			metadata: map[string][]byte{"egress_port": {0x02, 0x00}, "flags": {0x01}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cpm.Encode(tt.metadata)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Encode() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !proto.Equal(got[i], tt.want[i]) {
+					t.Errorf("Encode()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestControllerPacketMetadataDecode(t *testing.T) {
+	cpm := GetControllerPacketMetadata(&configv1.ControllerPacketMetadata{
+		Preamble: &configv1.Preamble{Id: 2, Name: "packet_in"},
+		Metadata: []*configv1.ControllerPacketMetadata_Metadata{
+			{Id: 1, Name: "ingress_port", Bitwidth: 9},
+			{Id: 2, Name: "reason", Bitwidth: 8},
+		},
+	})
+	tests := []struct {
+		name     string
+		metadata []*v1.PacketMetadata
+		want     map[string][]byte
+		wantErr  bool
+	}{
+		{
+			name:     "all metadata",
+			metadata: []*v1.PacketMetadata{{MetadataId: 2, Value: []byte{0x03}}, {MetadataId: 1, Value: []byte{0x01, 0x00}}},
+			want:     map[string][]byte{"ingress_port": {0x01, 0x00}, "reason": {0x03}},
+		},
+		{
+			name:     "no metadata",
+			metadata: nil,
+			want:     map[string][]byte{},
+		},
+		{
+			name:     "unknown metadata ID",
+			metadata: []*v1.PacketMetadata{{MetadataId: 3, Value: []byte{0x01}}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := cpm.Decode(tt.metadata)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPacketOut(t *testing.T) {
+	cpm := testPacketOutMetadata()
+	payload := []byte{0xde, 0xad, 0xbe, 0xef}
+	got, err := cpm.PacketOut(payload, map[string][]byte{"egress_port": {0x00, 0x02}, "flags": {0x00}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &v1.StreamMessageRequest{Update: &v1.StreamMessageRequest_Packet{Packet: &v1.PacketOut{
+		Payload:  payload,
+		Metadata: []*v1.PacketMetadata{{MetadataId: 1, Value: []byte{0x02}}, {MetadataId: 2, Value: []byte{0x00}}},
+	}}}
+	if !proto.Equal(got, want) {
+		t.Errorf("PacketOut() = %v, want %v", got, want)
+	}
+
+	if _, err := cpm.PacketOut(payload, nil); err == nil {
+		t.Error("PacketOut() without metadata succeeded, want an error for the missing metadata")
+	}
+}
@@ -0,0 +1,111 @@
+package entity
+
+import (
+	"fmt"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// Register 保存 P4Info 中一个 register 数组的信息：
+//   - ID：register 的唯一标识符。
+//   - Name：register 的名称。
+//   - Size：数组大小。
+//   - TypeSpec：每个元素的类型描述。
+//   - Fields：由 TypeSpec 展开的成员列表；元素为 bit<W> 等标量类型时只有一个名为 ValueField 的成员。
+type Register struct {
+	ID       uint32
+	Name     string
+	Size     int32
+	TypeSpec *configv1.P4DataTypeSpec
+	Fields   []DataField
+}
+
+// ReadValueWithIndex 用于读取特定索引处的 register 值
+func (r *Register) ReadValueWithIndex(index int64) *v1.Entity {
+	entry := &v1.RegisterEntry{
+		RegisterId: r.ID,
+		Index:      &v1.Index{Index: index},
+	}
+	return &v1.Entity{
+		Entity: &v1.Entity_RegisterEntry{RegisterEntry: entry},
+	}
+}
+
+// ReadValue 读取所有索引处的 register 值
+func (r *Register) ReadValue() *v1.Entity {
+	entry := &v1.RegisterEntry{
+		RegisterId: r.ID,
+	}
+	return &v1.Entity{
+		Entity: &v1.Entity_RegisterEntry{RegisterEntry: entry},
+	}
+}
+
+// WriteValueWithIndex 将以成员名称为键的 values 写入特定索引处，值的类型见 encodeP4Data
+func (r *Register) WriteValueWithIndex(index int64, values map[string]interface{}) (*v1.Update, error) {
+	data, err := encodeP4Data(r.TypeSpec, r.Fields, values)
+	if err != nil {
+		return nil, fmt.Errorf("register %s: %v", r.Name, err)
+	}
+	entry := &v1.RegisterEntry{
+		RegisterId: r.ID,
+		Index:      &v1.Index{Index: index},
+		Data:       data,
+	}
+	return &v1.Update{
+		Type: v1.Update_MODIFY,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_RegisterEntry{RegisterEntry: entry},
+		},
+	}, nil
+}
+
+// Reset 返回将整个 register 数组恢复为初始值的更新：不带索引（即所有元素）的 MODIFY 请求，
+// data 为元素类型的初始值（见 zeroValues）。P4Runtime 要求 data 与 type_spec 一致，因此不能省略。
+func (r *Register) Reset() (*v1.Update, error) {
+	data, err := encodeP4Data(r.TypeSpec, r.Fields, zeroValues(r.Fields))
+	if err != nil {
+		return nil, fmt.Errorf("register %s: %v", r.Name, err)
+	}
+	entry := &v1.RegisterEntry{
+		RegisterId: r.ID,
+		Data:       data,
+	}
+	return &v1.Update{
+		Type: v1.Update_MODIFY,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_RegisterEntry{RegisterEntry: entry},
+		},
+	}, nil
+}
+
+// Decode 将交换机返回的 P4Data 解码为以成员名称为键的值，值的类型与 Digest.Decode 相同
+func (r *Register) Decode(data *v1.P4Data) (map[string]interface{}, error) {
+	if r.Fields == nil {
+		return nil, fmt.Errorf("register %s has an unsupported type_spec", r.Name)
+	}
+	values, err := decodeP4Data(r.Fields, data)
+	if err != nil {
+		return nil, fmt.Errorf("register %s: %v", r.Name, err)
+	}
+	return values, nil
+}
+
+func (r *Register) Type() string {
+	return "REGISTER"
+}
+
+func (r *Register) GetID() uint32 {
+	return r.ID
+}
+
+func GetRegister(register *configv1.Register, typeInfo *configv1.P4TypeInfo) Register {
+	return Register{
+		ID:       register.Preamble.Id,
+		Name:     register.Preamble.Name,
+		Size:     register.Size,
+		TypeSpec: register.TypeSpec,
+		Fields:   getDataFields(register.TypeSpec, typeInfo),
+	}
+}
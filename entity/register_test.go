@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"testing"
+
+	configv1 "github.com/p4lang/p4runtime/go/p4/config/v1"
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestRegisterReset(t *testing.T) {
+	structSpec, typeInfo := testDigestSpec()
+	boolSpec := &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_Bool{Bool: &configv1.P4BoolType{}}}
+
+	tests := []struct {
+		name     string
+		spec     *configv1.P4DataTypeSpec
+		typeInfo *configv1.P4TypeInfo
+		want     *v1.P4Data
+		wantErr  bool
+	}{
+		{"bit<32>", bitSpec(32), nil, bits(0x00), false},
+		{"int<8>", intSpec(8), nil, bits(0x00), false},
+		{"bool", boolSpec, nil, &v1.P4Data{Data: &v1.P4Data_Bool{Bool: false}}, false},
+		{
+			name:     "nested struct with header",
+			spec:     structSpec,
+			typeInfo: typeInfo,
+			want: structData(bits(0x00), bits(0x00), &v1.P4Data{Data: &v1.P4Data_Bool{Bool: false}},
+				structData(bits(0x00), bits(0x00)), headerData(false)),
+		},
+		{
+			name:    "unsupported type_spec",
+			spec:    &configv1.P4DataTypeSpec{TypeSpec: &configv1.P4DataTypeSpec_SerializableEnum{}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Register{ID: 7, Name: "r", Size: 16, TypeSpec: tt.spec, Fields: getDataFields(tt.spec, tt.typeInfo)}
+			update, err := r.Reset()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if update.Type != v1.Update_MODIFY {
+				t.Errorf("Reset() update type = %v, want MODIFY", update.Type)
+			}
+			entry := update.GetEntity().GetRegisterEntry()
+			if entry.GetRegisterId() != r.ID {
+				t.Errorf("Reset() register ID = %d, want %d", entry.GetRegisterId(), r.ID)
+			}
+			if entry.GetIndex() != nil {
+				t.Errorf("Reset() index = %v, want unset (all elements)", entry.GetIndex())
+			}
+			if entry.GetData() == nil {
+				t.Fatal("Reset() data is unset, want the zero value of the type_spec")
+			}
+			if !proto.Equal(entry.GetData(), tt.want) {
+				t.Errorf("Reset() data = %v, want %v", entry.GetData(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package entity
+
+import (
+	"github.com/p4lang/p4runtime/go/p4/v1"
+)
+
+// Replica 是报文复制引擎（PRE）中的一个副本：
+//   - Port：出端口，按 P4Runtime 的端口字节串表示；为 nil 时使用 EgressPort。
+//   - EgressPort：以 uint32 表示的出端口，P4Runtime 已不推荐使用。
+//   - Instance：副本的实例号，用于区分发往同一端口的多个副本。
+type Replica struct {
+	Port       []byte
+	EgressPort uint32
+	Instance   uint32
+}
+
+func (r Replica) toP4() *v1.Replica {
+	replica := &v1.Replica{Instance: r.Instance}
+	if r.Port != nil {
+		replica.PortKind = &v1.Replica_Port{Port: r.Port}
+	} else {
+		replica.PortKind = &v1.Replica_EgressPort{EgressPort: r.EgressPort}
+	}
+	return replica
+}
+
+func replicasToP4(replicas []Replica) []*v1.Replica {
+	result := make([]*v1.Replica, 0, len(replicas))
+	for _, r := range replicas {
+		result = append(result, r.toP4())
+	}
+	return result
+}
+
+func decodeReplicas(replicas []*v1.Replica) []Replica {
+	result := make([]Replica, 0, len(replicas))
+	for _, r := range replicas {
+		result = append(result, Replica{
+			Port:       r.GetPort(),
+			EgressPort: r.GetEgressPort(),
+			Instance:   r.Instance,
+		})
+	}
+	return result
+}
+
+func preUpdate(updateType v1.Update_Type, entry *v1.PacketReplicationEngineEntry) *v1.Update {
+	return &v1.Update{
+		Type: updateType,
+		Entity: &v1.Entity{
+			Entity: &v1.Entity_PacketReplicationEngineEntry{PacketReplicationEngineEntry: entry},
+		},
+	}
+}
+
+// MulticastGroup 是一个多播组，用于泛洪、广播等需要将报文复制到多个端口的场景：
+//   - ID：多播组 ID，P4 程序通过 standard_metadata.mcast_grp 等引用，0 为无效值。
+//   - Replicas：组内的副本。
+type MulticastGroup struct {
+	ID       uint32
+	Replicas []Replica
+}
+
+func (mg *MulticastGroup) entry() *v1.PacketReplicationEngineEntry {
+	return &v1.PacketReplicationEngineEntry{
+		Type: &v1.PacketReplicationEngineEntry_MulticastGroupEntry{MulticastGroupEntry: &v1.MulticastGroupEntry{
+			MulticastGroupId: mg.ID,
+			Replicas:         replicasToP4(mg.Replicas),
+		}},
+	}
+}
+
+// Insert 插入多播组
+func (mg *MulticastGroup) Insert() *v1.Update {
+	return preUpdate(v1.Update_INSERT, mg.entry())
+}
+
+// Modify 替换多播组的副本列表
+func (mg *MulticastGroup) Modify() *v1.Update {
+	return preUpdate(v1.Update_MODIFY, mg.entry())
+}
+
+// Delete 删除多播组
+func (mg *MulticastGroup) Delete() *v1.Update {
+	group := MulticastGroup{ID: mg.ID}
+	return preUpdate(v1.Update_DELETE, group.entry())
+}
+
+// Read 读取多播组，ID 为 0 时读取所有多播组
+func (mg *MulticastGroup) Read() *v1.Entity {
+	group := MulticastGroup{ID: mg.ID}
+	return &v1.Entity{
+		Entity: &v1.Entity_PacketReplicationEngineEntry{PacketReplicationEngineEntry: group.entry()},
+	}
+}
+
+// CloneSession 是一个克隆会话，用于镜像等需要将报文副本发送到其他端口的场景：
+//   - ID：会话 ID，0 为无效值。
+//   - Replicas：会话的副本。
+//   - ClassOfService：克隆报文的服务等级。
+//   - PacketLengthBytes：克隆报文截断后的最大长度，0 表示不截断。
+type CloneSession struct {
+	ID                uint32
+	Replicas          []Replica
+	ClassOfService    uint32
+	PacketLengthBytes int32
+}
+
+func (cs *CloneSession) entry() *v1.PacketReplicationEngineEntry {
+	return &v1.PacketReplicationEngineEntry{
+		Type: &v1.PacketReplicationEngineEntry_CloneSessionEntry{CloneSessionEntry: &v1.CloneSessionEntry{
+			SessionId:         cs.ID,
+			Replicas:          replicasToP4(cs.Replicas),
+			ClassOfService:    cs.ClassOfService,
+			PacketLengthBytes: cs.PacketLengthBytes,
+		}},
+	}
+}
+
+// Insert 插入克隆会话
+func (cs *CloneSession) Insert() *v1.Update {
+	return preUpdate(v1.Update_INSERT, cs.entry())
+}
+
+// Modify 修改克隆会话
+func (cs *CloneSession) Modify() *v1.Update {
+	return preUpdate(v1.Update_MODIFY, cs.entry())
+}
+
+// Delete 删除克隆会话
+func (cs *CloneSession) Delete() *v1.Update {
+	session := CloneSession{ID: cs.ID}
+	return preUpdate(v1.Update_DELETE, session.entry())
+}
+
+// Read 读取克隆会话，ID 为 0 时读取所有克隆会话
+func (cs *CloneSession) Read() *v1.Entity {
+	session := CloneSession{ID: cs.ID}
+	return &v1.Entity{
+		Entity: &v1.Entity_PacketReplicationEngineEntry{PacketReplicationEngineEntry: session.entry()},
+	}
+}
+
+// DecodeMulticastGroup 将交换机返回的多播组表项还原为 MulticastGroup
+func DecodeMulticastGroup(entry *v1.MulticastGroupEntry) MulticastGroup {
+	return MulticastGroup{
+		ID:       entry.MulticastGroupId,
+		Replicas: decodeReplicas(entry.Replicas),
+	}
+}
+
+// DecodeCloneSession 将交换机返回的克隆会话表项还原为 CloneSession
+func DecodeCloneSession(entry *v1.CloneSessionEntry) CloneSession {
+	return CloneSession{
+		ID:                entry.SessionId,
+		Replicas:          decodeReplicas(entry.Replicas),
+		ClassOfService:    entry.ClassOfService,
+		PacketLengthBytes: entry.PacketLengthBytes,
+	}
+}
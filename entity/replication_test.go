@@ -0,0 +1,111 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/p4lang/p4runtime/go/p4/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func preEntity(entry *v1.PacketReplicationEngineEntry) *v1.Entity {
+	return &v1.Entity{Entity: &v1.Entity_PacketReplicationEngineEntry{PacketReplicationEngineEntry: entry}}
+}
+
+func multicastEntry(id uint32, replicas ...*v1.Replica) *v1.PacketReplicationEngineEntry {
+	return &v1.PacketReplicationEngineEntry{Type: &v1.PacketReplicationEngineEntry_MulticastGroupEntry{
+		MulticastGroupEntry: &v1.MulticastGroupEntry{MulticastGroupId: id, Replicas: replicas},
+	}}
+}
+
+func cloneEntry(entry *v1.CloneSessionEntry) *v1.PacketReplicationEngineEntry {
+	return &v1.PacketReplicationEngineEntry{Type: &v1.PacketReplicationEngineEntry_CloneSessionEntry{CloneSessionEntry: entry}}
+}
+
+func TestReplicationUpdates(t *testing.T) {
+	group := &MulticastGroup{ID: 1, Replicas: []Replica{
+		{EgressPort: 1, Instance: 1},
+		{Port: []byte{0x02}, Instance: 2},
+	}}
+	session := &CloneSession{ID: 5, Replicas: []Replica{{EgressPort: 255}}, ClassOfService: 3, PacketLengthBytes: 128}
+
+	p4Replicas := []*v1.Replica{
+		{PortKind: &v1.Replica_EgressPort{EgressPort: 1}, Instance: 1},
+		{PortKind: &v1.Replica_Port{Port: []byte{0x02}}, Instance: 2},
+	}
+	p4Session := &v1.CloneSessionEntry{
+		SessionId:         5,
+		Replicas:          []*v1.Replica{{PortKind: &v1.Replica_EgressPort{EgressPort: 255}}},
+		ClassOfService:    3,
+		PacketLengthBytes: 128,
+	}
+	update := func(updateType v1.Update_Type, entry *v1.PacketReplicationEngineEntry) *v1.Update {
+		return &v1.Update{Type: updateType, Entity: preEntity(entry)}
+	}
+
+	tests := []struct {
+		name string
+		got  proto.Message
+		want proto.Message
+	}{
+		{"insert multicast group", group.Insert(), update(v1.Update_INSERT, multicastEntry(1, p4Replicas...))},
+		{"modify multicast group", group.Modify(), update(v1.Update_MODIFY, multicastEntry(1, p4Replicas...))},
+		{"delete multicast group by ID", group.Delete(), update(v1.Update_DELETE, multicastEntry(1))},
+		{"read multicast group by ID", group.Read(), preEntity(multicastEntry(1))},
+		{"read all multicast groups", (&MulticastGroup{}).Read(), preEntity(multicastEntry(0))},
+		{"insert clone session", session.Insert(), update(v1.Update_INSERT, cloneEntry(p4Session))},
+		{"modify clone session", session.Modify(), update(v1.Update_MODIFY, cloneEntry(p4Session))},
+		{"delete clone session by ID", session.Delete(), update(v1.Update_DELETE, cloneEntry(&v1.CloneSessionEntry{SessionId: 5}))},
+		{"read clone session by ID", session.Read(), preEntity(cloneEntry(&v1.CloneSessionEntry{SessionId: 5}))},
+		{"read all clone sessions", (&CloneSession{}).Read(), preEntity(cloneEntry(&v1.CloneSessionEntry{}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !proto.Equal(tt.got, tt.want) {
+				t.Errorf("got %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeReplication(t *testing.T) {
+	tests := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{
+			name: "multicast group with both port kinds",
+			got: DecodeMulticastGroup(multicastEntry(1,
+				&v1.Replica{PortKind: &v1.Replica_EgressPort{EgressPort: 1}, Instance: 1},
+				&v1.Replica{PortKind: &v1.Replica_Port{Port: []byte{0x02}}, Instance: 2},
+			).GetMulticastGroupEntry()),
+			want: MulticastGroup{ID: 1, Replicas: []Replica{
+				{EgressPort: 1, Instance: 1},
+				{Port: []byte{0x02}, Instance: 2},
+			}},
+		},
+		{
+			name: "empty multicast group",
+			got:  DecodeMulticastGroup(multicastEntry(2).GetMulticastGroupEntry()),
+			want: MulticastGroup{ID: 2, Replicas: []Replica{}},
+		},
+		{
+			name: "clone session",
+			got: DecodeCloneSession(&v1.CloneSessionEntry{
+				SessionId:         5,
+				Replicas:          []*v1.Replica{{PortKind: &v1.Replica_EgressPort{EgressPort: 255}}},
+				ClassOfService:    3,
+				PacketLengthBytes: 128,
+			}),
+			want: CloneSession{ID: 5, Replicas: []Replica{{EgressPort: 255}}, ClassOfService: 3, PacketLengthBytes: 128},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("got %+v, want %+v", tt.got, tt.want)
+			}
+		})
+	}
+}
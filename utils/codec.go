@@ -0,0 +1,393 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+)
+
+// P4Runtime 要求所有 bytestring 使用规范（canonical）形式发送：大端序、去掉前导零字节，
+// 值为 0 时用单个 0x00 字节表示。下面的函数根据 P4Info 中声明的位宽对值进行编码和解码，
+// 并拒绝超出位宽的值。
+
+// Canonicalize 将大端序字节串转换为规范形式，并检查其是否能放入 bitwidth 位。
+// bitwidth 小于等于 0 时不做位宽检查。
+func Canonicalize(b []byte, bitwidth int32) ([]byte, error) {
+	i := 0
+	for i < len(b)-1 && b[i] == 0 {
+		i++
+	}
+	canonical := b[i:]
+	if len(canonical) == 0 {
+		canonical = []byte{0}
+	}
+
+	if bitwidth > 0 && bitLen(canonical) > int(bitwidth) {
+		return nil, fmt.Errorf("value 0x%x does not fit in %d bits", canonical, bitwidth)
+	}
+
+	result := make([]byte, len(canonical))
+	copy(result, canonical)
+	return result, nil
+}
+
+// bitLen 返回规范形式字节串的有效位数
+func bitLen(canonical []byte) int {
+	first := canonical[0]
+	n := 0
+	for first != 0 {
+		first >>= 1
+		n++
+	}
+	return n + 8*(len(canonical)-1)
+}
+
+// EncodeUint64 将无符号整数编码为位宽为 bitwidth 的规范字节串
+func EncodeUint64(v uint64, bitwidth int32) ([]byte, error) {
+	return EncodeBigInt(new(big.Int).SetUint64(v), bitwidth)
+}
+
+// EncodeBigInt 将任意精度的非负整数编码为位宽为 bitwidth 的规范字节串
+func EncodeBigInt(v *big.Int, bitwidth int32) ([]byte, error) {
+	if v.Sign() < 0 {
+		return nil, fmt.Errorf("negative value %s cannot be encoded", v)
+	}
+	return Canonicalize(v.Bytes(), bitwidth)
+}
+
+// EncodeIPv4 将 IPv4 地址编码为规范字节串，字段位宽必须为 32
+func EncodeIPv4(ip net.IP, bitwidth int32) ([]byte, error) {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("not an IPv4 address: %s", ip)
+	}
+	if bitwidth > 0 && bitwidth != 8*net.IPv4len {
+		return nil, fmt.Errorf("IPv4 address %s supplied for %d-bit field", ip, bitwidth)
+	}
+	return Canonicalize(ip4, bitwidth)
+}
+
+// EncodeIPv6 将 IPv6 地址编码为规范字节串，字段位宽必须为 128
+func EncodeIPv6(ip net.IP, bitwidth int32) ([]byte, error) {
+	if ip.To4() != nil || len(ip) != net.IPv6len {
+		return nil, fmt.Errorf("not an IPv6 address: %s", ip)
+	}
+	if bitwidth > 0 && bitwidth != 8*net.IPv6len {
+		return nil, fmt.Errorf("IPv6 address %s supplied for %d-bit field", ip, bitwidth)
+	}
+	return Canonicalize(ip, bitwidth)
+}
+
+// macLen 是 MAC 地址的字节数
+const macLen = 6
+
+// EncodeMAC 将 MAC 地址编码为规范字节串
+func EncodeMAC(mac net.HardwareAddr, bitwidth int32) ([]byte, error) {
+	return Canonicalize(mac, bitwidth)
+}
+
+// EncodeString 解析字符串并编码为规范字节串，支持以下格式：
+//   - IPv4/IPv6 地址，例如 "10.0.0.1"、"2001:db8::1"
+//   - MAC 地址，例如 "00:11:22:33:44:55"
+//   - 十进制或 0x 前缀的十六进制整数，例如 "42"、"0x2a"
+func EncodeString(s string, bitwidth int32) ([]byte, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		if ip.To4() != nil {
+			return EncodeIPv4(ip, bitwidth)
+		}
+		return EncodeIPv6(ip, bitwidth)
+	}
+	if mac, err := net.ParseMAC(s); err == nil {
+		return EncodeMAC(mac, bitwidth)
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		digits := s[2:]
+		if len(digits)%2 == 1 {
+			digits = "0" + digits
+		}
+		b, err := hex.DecodeString(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hex value %q: %v", s, err)
+		}
+		return Canonicalize(b, bitwidth)
+	}
+	v, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil, fmt.Errorf("cannot encode %q as a P4Runtime bytestring", s)
+	}
+	return EncodeBigInt(v, bitwidth)
+}
+
+// Encode 根据值的类型选择合适的编码方式，支持各种无符号/有符号整数、*big.Int、
+// net.IP、net.HardwareAddr、string 和 []byte。
+func Encode(value interface{}, bitwidth int32) ([]byte, error) {
+	switch v := value.(type) {
+	case uint8:
+		return EncodeUint64(uint64(v), bitwidth)
+	case uint16:
+		return EncodeUint64(uint64(v), bitwidth)
+	case uint32:
+		return EncodeUint64(uint64(v), bitwidth)
+	case uint64:
+		return EncodeUint64(v, bitwidth)
+	case uint:
+		return EncodeUint64(uint64(v), bitwidth)
+	case int:
+		return EncodeBigInt(big.NewInt(int64(v)), bitwidth)
+	case int8:
+		return EncodeBigInt(big.NewInt(int64(v)), bitwidth)
+	case int16:
+		return EncodeBigInt(big.NewInt(int64(v)), bitwidth)
+	case int32:
+		return EncodeBigInt(big.NewInt(int64(v)), bitwidth)
+	case int64:
+		return EncodeBigInt(big.NewInt(v), bitwidth)
+	case *big.Int:
+		return EncodeBigInt(v, bitwidth)
+	case net.IP:
+		if v.To4() != nil {
+			return EncodeIPv4(v, bitwidth)
+		}
+		return EncodeIPv6(v, bitwidth)
+	case net.HardwareAddr:
+		return EncodeMAC(v, bitwidth)
+	case string:
+		return EncodeString(v, bitwidth)
+	case []byte:
+		return Canonicalize(v, bitwidth)
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// EncodeSignedBigInt 将任意精度的整数按二进制补码编码为 int<bitwidth> 的规范字节串，
+// 值必须在 [-2^(bitwidth-1), 2^(bitwidth-1)) 范围内
+func EncodeSignedBigInt(v *big.Int, bitwidth int32) ([]byte, error) {
+	if bitwidth <= 0 {
+		return nil, fmt.Errorf("signed value %s requires a bitwidth", v)
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bitwidth-1))
+	if v.Cmp(limit) >= 0 || v.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, fmt.Errorf("value %s does not fit in int<%d>", v, bitwidth)
+	}
+	if v.Sign() < 0 {
+		v = new(big.Int).Add(v, new(big.Int).Lsh(limit, 1))
+	}
+	return Canonicalize(v.Bytes(), bitwidth)
+}
+
+// EncodeSigned 与 Encode 类似，用于 int<W> 类型的字段：整数和十进制字符串按二进制补码编码，允许负值；
+// 其他类型的值（地址、十六进制字符串、[]byte 等）按原始比特编码，与 Encode 相同。
+func EncodeSigned(value interface{}, bitwidth int32) ([]byte, error) {
+	switch v := value.(type) {
+	case int:
+		return EncodeSignedBigInt(big.NewInt(int64(v)), bitwidth)
+	case int8:
+		return EncodeSignedBigInt(big.NewInt(int64(v)), bitwidth)
+	case int16:
+		return EncodeSignedBigInt(big.NewInt(int64(v)), bitwidth)
+	case int32:
+		return EncodeSignedBigInt(big.NewInt(int64(v)), bitwidth)
+	case int64:
+		return EncodeSignedBigInt(big.NewInt(v), bitwidth)
+	case uint8:
+		return EncodeSignedBigInt(new(big.Int).SetUint64(uint64(v)), bitwidth)
+	case uint16:
+		return EncodeSignedBigInt(new(big.Int).SetUint64(uint64(v)), bitwidth)
+	case uint32:
+		return EncodeSignedBigInt(new(big.Int).SetUint64(uint64(v)), bitwidth)
+	case uint64:
+		return EncodeSignedBigInt(new(big.Int).SetUint64(v), bitwidth)
+	case uint:
+		return EncodeSignedBigInt(new(big.Int).SetUint64(uint64(v)), bitwidth)
+	case *big.Int:
+		return EncodeSignedBigInt(v, bitwidth)
+	case string:
+		if i, ok := new(big.Int).SetString(v, 10); ok {
+			return EncodeSignedBigInt(i, bitwidth)
+		}
+	}
+	return Encode(value, bitwidth)
+}
+
+// padTo 将规范字节串左侧补零至 n 字节
+func padTo(b []byte, n int) ([]byte, error) {
+	if len(b) > n {
+		canonical, err := Canonicalize(b, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(canonical) > n {
+			return nil, fmt.Errorf("value 0x%x is longer than %d bytes", b, n)
+		}
+		b = canonical
+	}
+	result := make([]byte, n)
+	copy(result[n-len(b):], b)
+	return result, nil
+}
+
+// 下面的 Decode 函数与对应的 Encode 函数一一对应：它们接受同样的 bitwidth，
+// 拒绝超出位宽的值，并满足 Encode(Decode(b)) 得到 b 的规范形式。
+
+// DecodeUint64 将交换机返回的大端序字节串解码为无符号整数，是 EncodeUint64 的逆操作
+func DecodeUint64(b []byte, bitwidth int32) (uint64, error) {
+	v, err := DecodeBigInt(b, bitwidth)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsUint64() {
+		return 0, fmt.Errorf("value 0x%x overflows uint64", b)
+	}
+	return v.Uint64(), nil
+}
+
+// DecodeBigInt 将交换机返回的大端序字节串解码为任意精度的非负整数，是 EncodeBigInt 的逆操作
+func DecodeBigInt(b []byte, bitwidth int32) (*big.Int, error) {
+	canonical, err := Canonicalize(b, bitwidth)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(canonical), nil
+}
+
+// DecodeInt64 将 int<bitwidth> 类型的字节串按二进制补码解码为有符号整数，是 EncodeSigned 的逆操作
+func DecodeInt64(b []byte, bitwidth int32) (int64, error) {
+	v, err := DecodeSignedBigInt(b, bitwidth)
+	if err != nil {
+		return 0, err
+	}
+	if !v.IsInt64() {
+		return 0, fmt.Errorf("value 0x%x overflows int64", b)
+	}
+	return v.Int64(), nil
+}
+
+// DecodeSignedBigInt 将 int<bitwidth> 类型的字节串按二进制补码解码为任意精度的整数，是 EncodeSignedBigInt 的逆操作
+func DecodeSignedBigInt(b []byte, bitwidth int32) (*big.Int, error) {
+	if bitwidth <= 0 {
+		return nil, fmt.Errorf("signed value 0x%x requires a bitwidth", b)
+	}
+	v, err := DecodeBigInt(b, bitwidth)
+	if err != nil {
+		return nil, err
+	}
+	if v.Bit(int(bitwidth-1)) == 1 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(bitwidth)))
+	}
+	return v, nil
+}
+
+// DecodeIPv4 将交换机返回的字节串解码为 IPv4 地址，是 EncodeIPv4 的逆操作，字段位宽必须为 32
+func DecodeIPv4(b []byte, bitwidth int32) (net.IP, error) {
+	if bitwidth > 0 && bitwidth != 8*net.IPv4len {
+		return nil, fmt.Errorf("%d-bit value cannot be decoded as an IPv4 address", bitwidth)
+	}
+	ip, err := padTo(b, net.IPv4len)
+	if err != nil {
+		return nil, err
+	}
+	return net.IPv4(ip[0], ip[1], ip[2], ip[3]).To4(), nil
+}
+
+// DecodeIPv6 将交换机返回的字节串解码为 IPv6 地址，是 EncodeIPv6 的逆操作，字段位宽必须为 128
+func DecodeIPv6(b []byte, bitwidth int32) (net.IP, error) {
+	if bitwidth > 0 && bitwidth != 8*net.IPv6len {
+		return nil, fmt.Errorf("%d-bit value cannot be decoded as an IPv6 address", bitwidth)
+	}
+	ip, err := padTo(b, net.IPv6len)
+	if err != nil {
+		return nil, err
+	}
+	return net.IP(ip), nil
+}
+
+// DecodeMAC 将交换机返回的字节串解码为 MAC 地址，是 EncodeMAC 的逆操作
+func DecodeMAC(b []byte, bitwidth int32) (net.HardwareAddr, error) {
+	canonical, err := Canonicalize(b, bitwidth)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := padTo(canonical, macLen)
+	if err != nil {
+		return nil, err
+	}
+	return net.HardwareAddr(mac), nil
+}
+
+// StringFormat 是 DecodeString 输出的字符串格式
+type StringFormat int
+
+const (
+	// FormatDecimal 十进制整数，是默认格式
+	FormatDecimal StringFormat = iota
+	// FormatHex 带 0x 前缀的十六进制整数
+	FormatHex
+	// FormatIPv4 点分十进制的 IPv4 地址，要求位宽为 32
+	FormatIPv4
+	// FormatIPv6 IPv6 地址，要求位宽为 128
+	FormatIPv6
+	// FormatMAC 冒号分隔的 MAC 地址，要求位宽不超过 48
+	FormatMAC
+)
+
+// ParseStringFormat 将格式名称 "decimal"、"hex"、"ipv4"、"ipv6" 和 "mac" 转换为 StringFormat
+func ParseStringFormat(name string) (StringFormat, error) {
+	switch name {
+	case "decimal":
+		return FormatDecimal, nil
+	case "hex":
+		return FormatHex, nil
+	case "ipv4":
+		return FormatIPv4, nil
+	case "ipv6":
+		return FormatIPv6, nil
+	case "mac":
+		return FormatMAC, nil
+	default:
+		return 0, fmt.Errorf("unknown string format %q", name)
+	}
+}
+
+// DecodeString 将字节串按 format 解码为 EncodeString 能够解析的字符串，是 EncodeString 的逆操作。
+// 相同的值可能是地址也可能只是整数，位宽无法区分两者，因此格式由调用者指定，未知的格式按十进制整数解码。
+// 地址格式要求位宽与地址长度一致，否则返回错误。对于这些格式的输入，EncodeString 和 DecodeString 可以互相还原。
+func DecodeString(b []byte, bitwidth int32, format StringFormat) (string, error) {
+	switch format {
+	case FormatHex:
+		v, err := DecodeBigInt(b, bitwidth)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("0x%x", v), nil
+	case FormatIPv4:
+		ip, err := DecodeIPv4(b, bitwidth)
+		if err != nil {
+			return "", err
+		}
+		return ip.String(), nil
+	case FormatMAC:
+		mac, err := DecodeMAC(b, bitwidth)
+		if err != nil {
+			return "", err
+		}
+		return mac.String(), nil
+	case FormatIPv6:
+		ip, err := DecodeIPv6(b, bitwidth)
+		if err != nil {
+			return "", err
+		}
+		// IPv4 映射地址会被格式化为点分十进制，EncodeString 无法将其还原为 128 位的值
+		if ip.To4() != nil {
+			return "0x" + hex.EncodeToString(ip), nil
+		}
+		return ip.String(), nil
+	}
+	v, err := DecodeBigInt(b, bitwidth)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"bytes"
+	"math/big"
+	"net"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       []byte
+		bitwidth int32
+		want     []byte
+		wantErr  bool
+	}{
+		{"strips leading zeros", []byte{0, 0, 0x0a, 0x01}, 32, []byte{0x0a, 0x01}, false},
+		{"zero is one byte", []byte{0, 0, 0}, 16, []byte{0}, false},
+		{"empty is zero", nil, 8, []byte{0}, false},
+		{"fits exactly", []byte{0x0f, 0xff}, 12, []byte{0x0f, 0xff}, false},
+		{"overflows bitwidth", []byte{0x1f, 0xff}, 12, nil, true},
+		{"no bitwidth check", []byte{0xff, 0xff, 0xff}, 0, []byte{0xff, 0xff, 0xff}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Canonicalize(tt.in, tt.bitwidth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Canonicalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Canonicalize() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		bitwidth int32
+		want     []byte
+		wantErr  bool
+	}{
+		{"uint16", uint16(0x0102), 16, []byte{0x01, 0x02}, false},
+		{"int", 42, 9, []byte{0x2a}, false},
+		{"negative int", -1, 8, nil, true},
+		{"int8", int8(0x7f), 8, []byte{0x7f}, false},
+		{"negative int8", int8(-1), 8, nil, true},
+		{"int16", int16(0x0102), 16, []byte{0x01, 0x02}, false},
+		{"negative int16", int16(-1), 16, nil, true},
+		{"uint overflow", uint32(0x100), 8, nil, true},
+		{"big int", new(big.Int).Lsh(big.NewInt(1), 100), 101, append([]byte{0x10}, make([]byte, 12)...), false},
+		{"ipv4", net.ParseIP("10.0.0.1"), 32, []byte{0x0a, 0, 0, 0x01}, false},
+		{"ipv4 on 128-bit field", net.ParseIP("10.0.0.1"), 128, nil, true},
+		{"ipv6", net.ParseIP("::1"), 128, []byte{0x01}, false},
+		{"ipv6 on 32-bit field", net.ParseIP("2001:db8::1"), 32, nil, true},
+		{"mac", net.HardwareAddr{0, 0x11, 0x22, 0x33, 0x44, 0x55}, 48, []byte{0x11, 0x22, 0x33, 0x44, 0x55}, false},
+		{"decimal string", "300", 16, []byte{0x01, 0x2c}, false},
+		{"hex string", "0xabc", 12, []byte{0x0a, 0xbc}, false},
+		{"invalid string", "not-a-value", 16, nil, true},
+		{"bytes", []byte{0, 0x7f}, 7, []byte{0x7f}, false},
+		{"unsupported type", 1.5, 8, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Encode(tt.value, tt.bitwidth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Encode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("Encode() = %x, want %x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	if v, err := DecodeUint64([]byte{0, 0x01, 0x02}, 16); err != nil || v != 0x0102 {
+		t.Errorf("DecodeUint64() = %d, %v", v, err)
+	}
+	if _, err := DecodeUint64([]byte{0x01, 0x02}, 8); err == nil {
+		t.Error("DecodeUint64() accepted a value wider than the bitwidth")
+	}
+	if _, err := DecodeUint64(bytes.Repeat([]byte{0xff}, 9), 72); err == nil {
+		t.Error("DecodeUint64() accepted a value wider than 64 bits")
+	}
+	if ip, err := DecodeIPv4([]byte{0x0a, 0, 0, 0x01}, 32); err != nil || !ip.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("DecodeIPv4() = %v, %v", ip, err)
+	}
+	if _, err := DecodeIPv4([]byte{0x01}, 128); err == nil {
+		t.Error("DecodeIPv4() accepted a 128-bit field")
+	}
+	if _, err := DecodeIPv6([]byte{0x01}, 32); err == nil {
+		t.Error("DecodeIPv6() accepted a 32-bit field")
+	}
+	if mac, err := DecodeMAC([]byte{0x01}, 48); err != nil || mac.String() != "00:00:00:00:00:01" {
+		t.Errorf("DecodeMAC() = %v, %v", mac, err)
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		bitwidth int32
+		format   StringFormat
+		want     string
+	}{
+		{"ipv4", "10.0.0.1", 32, FormatIPv4, "10.0.0.1"},
+		{"ipv6", "2001:db8::1", 128, FormatIPv6, "2001:db8::1"},
+		{"ipv6 zero", "::", 128, FormatIPv6, "::"},
+		{"mac", "00:11:22:33:44:55", 48, FormatMAC, "00:11:22:33:44:55"},
+		{"decimal", "4095", 12, FormatDecimal, "4095"},
+		{"hex", "0x1ff", 9, FormatHex, "0x1ff"},
+		{"hex zero", "0", 9, FormatHex, "0x0"},
+		{"hex input decoded as decimal", "0x1ff", 9, FormatDecimal, "511"},
+		// 32 位和 48 位的值不一定是地址，默认按十进制整数解码
+		{"32-bit value is decimal by default", "10.0.0.1", 32, FormatDecimal, "167772161"},
+		{"48-bit value is decimal by default", "00:00:00:00:01:00", 48, FormatDecimal, "256"},
+		{"unknown format falls back to decimal", "42", 32, StringFormat(42), "42"},
+		{"ipv4-mapped ipv6", "0x00000000000000000000ffff0a000001", 128, FormatIPv6, "0x00000000000000000000ffff0a000001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := EncodeString(tt.in, tt.bitwidth)
+			if err != nil {
+				t.Fatalf("EncodeString() error = %v", err)
+			}
+			got, err := DecodeString(b, tt.bitwidth, tt.format)
+			if err != nil {
+				t.Fatalf("DecodeString() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DecodeString(EncodeString(%q)) = %q, want %q", tt.in, got, tt.want)
+			}
+			again, err := EncodeString(got, tt.bitwidth)
+			if err != nil || !bytes.Equal(again, b) {
+				t.Errorf("EncodeString(%q) = %x, %v, want %x", got, again, err, b)
+			}
+		})
+	}
+}
+
+func TestDecodeStringFormatMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		bitwidth int32
+		format   StringFormat
+	}{
+		{"ipv4 on 16-bit value", 16, FormatIPv4},
+		{"ipv6 on 32-bit value", 32, FormatIPv6},
+		{"mac on 64-bit value", 64, FormatMAC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := bytes.Repeat([]byte{0xff}, int(tt.bitwidth/8))
+			if got, err := DecodeString(b, tt.bitwidth, tt.format); err == nil {
+				t.Errorf("DecodeString() = %q, want an error", got)
+			}
+		})
+	}
+}
+
+func TestParseStringFormat(t *testing.T) {
+	for name, want := range map[string]StringFormat{
+		"decimal": FormatDecimal, "hex": FormatHex, "ipv4": FormatIPv4, "ipv6": FormatIPv6, "mac": FormatMAC,
+	} {
+		if got, err := ParseStringFormat(name); err != nil || got != want {
+			t.Errorf("ParseStringFormat(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseStringFormat("ip"); err == nil {
+		t.Error("ParseStringFormat() accepted an unknown format")
+	}
+}
+
+func TestSignedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		bitwidth int32
+		want     []byte
+		decoded  int64
+		wantErr  bool
+	}{
+		{"minus one", -1, 8, []byte{0xff}, -1, false},
+		{"minimum", int8(-128), 8, []byte{0x80}, -128, false},
+		{"maximum", int16(127), 8, []byte{0x7f}, 127, false},
+		{"non byte-aligned negative", -2, 12, []byte{0x0f, 0xfe}, -2, false},
+		{"negative decimal string", "-5", 16, []byte{0xff, 0xfb}, -5, false},
+		{"above maximum", 128, 8, nil, 0, true},
+		{"below minimum", int64(-129), 8, nil, 0, true},
+		{"unsigned above maximum", uint8(0xff), 8, nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncodeSigned(tt.value, tt.bitwidth)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncodeSigned() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("EncodeSigned() = %x, want %x", got, tt.want)
+			}
+			if tt.wantErr {
+				return
+			}
+			decoded, err := DecodeInt64(got, tt.bitwidth)
+			if err != nil || decoded != tt.decoded {
+				t.Errorf("DecodeInt64(%x) = %d, %v, want %d", got, decoded, err, tt.decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeSignedBigInt(t *testing.T) {
+	v, err := DecodeSignedBigInt(append([]byte{0x3f}, bytes.Repeat([]byte{0xff}, 8)...), 70)
+	if err != nil || v.Cmp(big.NewInt(-1)) != 0 {
+		t.Errorf("DecodeSignedBigInt() = %v, %v, want -1", v, err)
+	}
+	if _, err := DecodeSignedBigInt([]byte{0x01}, 0); err == nil {
+		t.Error("DecodeSignedBigInt() accepted a value without a bitwidth")
+	}
+}
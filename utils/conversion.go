@@ -4,14 +4,41 @@ import (
 	"encoding/binary"
 	"fmt"
 	"net"
+	"strings"
 )
 
+// IpToBinary 将 IP 地址字符串转换为字节串，IPv4 地址返回 4 字节，IPv6 地址返回 16 字节。
 func IpToBinary(ipStr string) ([]byte, error) {
 	ip := net.ParseIP(ipStr)
 	if ip == nil {
 		return nil, fmt.Errorf("Not a valid IP: %s", ipStr)
 	}
-	return []byte(ip.To4()), nil
+	if ip4 := ip.To4(); ip4 != nil {
+		return []byte(ip4), nil
+	}
+	return []byte(ip.To16()), nil
+}
+
+// PrefixToBinary 将 CIDR 格式的前缀（例如 "10.0.0.0/8"、"2001:db8::/32"）转换为字节串和前缀长度。
+// 不带前缀长度的地址视为主机路由（IPv4 为 /32，IPv6 为 /128）。
+func PrefixToBinary(prefixStr string) ([]byte, int32, error) {
+	if !strings.Contains(prefixStr, "/") {
+		value, err := IpToBinary(prefixStr)
+		if err != nil {
+			return nil, 0, err
+		}
+		return value, int32(len(value) * 8), nil
+	}
+
+	ip, ipNet, err := net.ParseCIDR(prefixStr)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Not a valid prefix: %s", prefixStr)
+	}
+	pLen, _ := ipNet.Mask.Size()
+	if ip.To4() != nil {
+		return []byte(ipNet.IP.To4()), int32(pLen), nil
+	}
+	return []byte(ipNet.IP.To16()), int32(pLen), nil
 }
 
 func MacToBinary(macStr string) ([]byte, error) {
@@ -22,16 +49,19 @@ func MacToBinary(macStr string) ([]byte, error) {
 	return []byte(mac), nil
 }
 
+// Deprecated: numBytes 是从 4 字节大端序表示中截掉的前缀字节数，容易误用；请使用 EncodeUint64。
 func UInt32ToBinary(i uint32, numBytes int) ([]byte, error) {
 	b := make([]byte, 4)
 	binary.BigEndian.PutUint32(b, i)
 	return b[numBytes:], nil
 }
 
+// Deprecated: 该函数按小端序解码，与 P4Runtime 使用的大端序不一致；请使用 DecodeUint64。
 func BinaryToUint32(data []byte) uint32 {
 	return uint32(uint32(data[0]) + uint32(data[1])<<8 + uint32(data[2])<<16 + uint32(data[3])<<24)
 }
 
+// Deprecated: 该函数按小端序解码，与 P4Runtime 使用的大端序不一致；请使用 DecodeUint64。
 func Binary48ToInt64(data []byte) uint64 {
 	return uint64(uint64(data[0]) + uint64(data[1])<<8 + uint64(data[2])<<16 + uint64(data[3])<<24 + uint64(data[4])<<32 + uint64(data[5])<<40)
 }